@@ -0,0 +1,152 @@
+package htmldoc
+
+import (
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// assetEntry : a file or directory found during discovery
+type assetEntry struct {
+	dir       bool // Is a directory, or a symlink to one
+	symlink   bool // Is a symlink, symlinked directories aren't walked
+	ignored   bool // Within a directory matching IgnorePatterns
+	unindexed bool // An ignored directory not walked, see IndexIgnored
+}
+
+// Record a directory entry in the asset index, thread safe. Symlinks are
+// resolved so links to directories are marked as such.
+func (dS *DocumentStore) indexAsset(sitePath string, fi os.FileInfo, ignored bool) assetEntry {
+	entry := assetEntry{dir: fi.IsDir(), ignored: ignored}
+	if fi.Mode()&os.ModeSymlink != 0 {
+		entry.symlink = true
+		if target, err := os.Stat(path.Join(dS.BasePath, sitePath)); err == nil {
+			entry.dir = target.IsDir()
+		}
+	}
+	lower := strings.ToLower(sitePath)
+	dS.storeMutex.Lock()
+	dS.assets[sitePath] = entry
+	dS.assetsLower[lower] = append(dS.assetsLower[lower], sitePath)
+	dS.storeMutex.Unlock()
+	return entry
+}
+
+// Note an ignored directory wasn't walked, so paths beneath it are looked up
+// on the filesystem. Thread safe.
+func (dS *DocumentStore) markUnindexed(sitePath string) {
+	dS.storeMutex.Lock()
+	if entry, ok := dS.assets[sitePath]; ok {
+		entry.unindexed = true
+		dS.assets[sitePath] = entry
+	}
+	dS.storeMutex.Unlock()
+}
+
+// Convert a reference path into an asset index key, "" being the root.
+func assetKey(refPath string) string {
+	return strings.TrimPrefix(path.Clean("/"+refPath), "/")
+}
+
+// ResolveAsset : Look up any file or directory under BasePath, documents
+// included, without touching the filesystem. Returns whether the path exists
+// and whether it is a directory. Paths are case sensitive. Those beneath
+// symlinked or unindexed ignored directories are looked up on the filesystem
+// instead. If discovery is
+// running and the path isn't found yet, waits for discovery to finish before
+// giving up.
+func (dS *DocumentStore) ResolveAsset(refPath string) (isDir bool, exists bool) {
+	key := assetKey(refPath)
+	isDir, exists = dS.resolveAsset(key)
+	if exists {
+		return isDir, exists
+	}
+	dS.WaitDiscovered()
+	isDir, exists = dS.resolveAsset(key)
+	if exists {
+		return isDir, exists
+	}
+	return dS.resolveAssetUnindexed(key)
+}
+
+// Single lookup of key in the asset index.
+func (dS *DocumentStore) resolveAsset(key string) (bool, bool) {
+	if key == "" {
+		return true, true
+	}
+	dS.storeMutex.RLock()
+	entry, ok := dS.assets[key]
+	dS.storeMutex.RUnlock()
+	return entry.dir, ok
+}
+
+// Paths beneath a symlinked or unindexed directory aren't indexed, fall back
+// to the filesystem for those.
+func (dS *DocumentStore) resolveAssetUnindexed(key string) (bool, bool) {
+	for dir := path.Dir(key); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		dS.storeMutex.RLock()
+		entry, ok := dS.assets[dir]
+		dS.storeMutex.RUnlock()
+		if ok && entry.dir && (entry.symlink || entry.unindexed) {
+			fi, err := os.Stat(path.Join(dS.BasePath, key))
+			if err != nil {
+				return false, false
+			}
+			return fi.IsDir(), true
+		}
+	}
+	return false, false
+}
+
+// AssetIgnored : Is refPath within a directory matching IgnorePatterns? Waits
+// for discovery to finish.
+func (dS *DocumentStore) AssetIgnored(refPath string) bool {
+	dS.WaitDiscovered()
+	key := assetKey(refPath)
+	dS.storeMutex.RLock()
+	defer dS.storeMutex.RUnlock()
+	if entry, ok := dS.assets[key]; ok && entry.ignored {
+		return true
+	}
+	// Ignored directories which weren't walked hold no entries
+	for dir := path.Dir(key); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if entry, ok := dS.assets[dir]; ok && entry.unindexed {
+			return true
+		}
+	}
+	return false
+}
+
+// AssetCaseMatches : Paths in the asset index which equal refPath ignoring
+// case, with a leading slash. Useful for explaining a miss from ResolveAsset.
+func (dS *DocumentStore) AssetCaseMatches(refPath string) []string {
+	dS.WaitDiscovered()
+	key := assetKey(refPath)
+	matches := make([]string, 0)
+	dS.storeMutex.RLock()
+	for _, p := range dS.assetsLower[strings.ToLower(key)] {
+		if p != key {
+			matches = append(matches, "/"+p)
+		}
+	}
+	dS.storeMutex.RUnlock()
+	sort.Strings(matches)
+	return matches
+}
+
+// AssetFiles : Sorted paths of every file in the asset index outside ignored
+// directories, documents included. Waits for discovery to finish.
+func (dS *DocumentStore) AssetFiles() []string {
+	dS.WaitDiscovered()
+	files := make([]string, 0)
+	dS.storeMutex.RLock()
+	for p, entry := range dS.assets {
+		if !entry.dir && !entry.ignored {
+			files = append(files, p)
+		}
+	}
+	dS.storeMutex.RUnlock()
+	sort.Strings(files)
+	return files
+}
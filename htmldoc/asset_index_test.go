@@ -0,0 +1,91 @@
+package htmldoc
+
+import (
+	"github.com/daviddengcn/go-assert"
+	"testing"
+)
+
+func tAssetStore() DocumentStore {
+	dS := NewDocumentStore()
+	dS.BasePath = "fixtures/documents"
+	dS.DocumentExtension = ".html"
+	dS.DirectoryIndex = "index.html"
+	dS.IgnorePatterns = []interface{}{"^lib/"}
+	dS.Discover()
+	return dS
+}
+
+func TestDocumentStoreResolveAsset(t *testing.T) {
+	// files and directories are found in the index
+	dS := tAssetStore()
+	isDir, exists := dS.ResolveAsset("/img.jpg")
+	assert.IsTrue(t, "img.jpg exists", exists)
+	assert.IsFalse(t, "img.jpg is a file", isDir)
+	isDir, exists = dS.ResolveAsset("dir1/dir11/")
+	assert.IsTrue(t, "dir1/dir11 exists", exists)
+	assert.IsTrue(t, "dir1/dir11 is a dir", isDir)
+	isDir, exists = dS.ResolveAsset("/")
+	assert.IsTrue(t, "root exists", exists)
+	assert.IsTrue(t, "root is a dir", isDir)
+	_, exists = dS.ResolveAsset("dir2/nope.png")
+	assert.IsFalse(t, "dir2/nope.png does not exist", exists)
+}
+
+func TestDocumentStoreResolveAssetIgnoredDir(t *testing.T) {
+	// ignored directories aren't walked, their files are found on disk
+	dS := tAssetStore()
+	_, indexed := dS.assets["lib/lib.js"]
+	assert.IsFalse(t, "lib/lib.js not indexed", indexed)
+	_, exists := dS.ResolveAsset("/lib/lib.js")
+	assert.IsTrue(t, "lib/lib.js exists", exists)
+	_, exists = dS.ResolveAsset("/lib/nope.js")
+	assert.IsFalse(t, "lib/nope.js does not exist", exists)
+}
+
+func TestDocumentStoreResolveAssetIndexIgnored(t *testing.T) {
+	// ignored directories are indexed when asked to
+	dS := NewDocumentStore()
+	dS.BasePath = "fixtures/documents"
+	dS.DocumentExtension = ".html"
+	dS.IgnorePatterns = []interface{}{"^lib/"}
+	dS.IndexIgnored = true
+	dS.Discover()
+	_, indexed := dS.assets["lib/lib.js"]
+	assert.IsTrue(t, "lib/lib.js indexed", indexed)
+	_, ok := dS.ResolvePath("/lib/unwanted-file.html")
+	assert.IsFalse(t, "no documents in lib", ok)
+}
+
+func TestDocumentStoreAssetIgnored(t *testing.T) {
+	// paths within ignored directories, walked or not
+	dS := tAssetStore()
+	assert.IsTrue(t, "lib/lib.js ignored", dS.AssetIgnored("/lib/lib.js"))
+	assert.IsFalse(t, "img.jpg not ignored", dS.AssetIgnored("/img.jpg"))
+	dS = NewDocumentStore()
+	dS.BasePath = "fixtures/documents"
+	dS.DocumentExtension = ".html"
+	dS.IgnorePatterns = []interface{}{"^lib/"}
+	dS.IndexIgnored = true
+	dS.Discover()
+	assert.IsTrue(t, "indexed lib/lib.js ignored", dS.AssetIgnored("/lib/lib.js"))
+}
+
+func TestDocumentStoreResolveAssetCase(t *testing.T) {
+	// lookups are case sensitive, case matches explain misses
+	dS := tAssetStore()
+	_, exists := dS.ResolveAsset("/IMG.jpg")
+	assert.IsFalse(t, "IMG.jpg does not exist", exists)
+	assert.StringEquals(t, "case matches", dS.AssetCaseMatches("/IMG.jpg"), []string{"/img.jpg"})
+	assert.Equals(t, "no case matches", len(dS.AssetCaseMatches("/img.jpg")), 0)
+}
+
+func TestDocumentStoreAssetFiles(t *testing.T) {
+	// lists files outside ignored directories
+	dS := tAssetStore()
+	files := dS.AssetFiles()
+	assert.Equals(t, "file count", len(files), 13)
+	assert.Equals(t, "first file", files[0], "contact.html")
+	for _, f := range files {
+		assert.NotEquals(t, "ignored file", f, "lib/lib.js")
+	}
+}
@@ -39,14 +39,8 @@ func AttrPresent(attrs []html.Attribute, key string) bool {
 	return false
 }
 
-// GetID : Get hash/fragment id from node.Attrs
+// GetID : Get hash/fragment id from node.Attrs. Only the id attribute counts,
+// name is a fragment identifier on <a> alone, see Document.parseNode.
 func GetID(attrs []html.Attribute) string {
-	for _, attr := range attrs {
-		if attr.Key == "id" {
-			return attr.Val
-		} else if attr.Key == "name" {
-			return attr.Val
-		}
-	}
-	return ""
+	return GetAttr(attrs, "id")
 }
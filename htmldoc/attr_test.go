@@ -50,5 +50,14 @@ func TestAttrValIdName(t *testing.T) {
 	nodeDoc, _ := html.Parse(strings.NewReader(snip))
 	nodeH1 := nodeDoc.FirstChild.FirstChild.NextSibling.FirstChild
 
-	assert.Equals(t, "h1 name", GetID(nodeH1.Attr), "x")
+	// name isn't an id, see TestDocumentHashTarget for <a name>
+	assert.Equals(t, "h1 name", GetID(nodeH1.Attr), "")
+}
+
+func TestAttrValIdNameAndId(t *testing.T) {
+	snip := "<a name=\"x\" id=\"y\" />"
+	nodeDoc, _ := html.Parse(strings.NewReader(snip))
+	nodeA := nodeDoc.FirstChild.FirstChild.NextSibling.FirstChild
+
+	assert.Equals(t, "a id", GetID(nodeA.Attr), "y")
 }
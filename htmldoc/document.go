@@ -6,6 +6,7 @@ import (
 	"golang.org/x/net/html"
 	"os"
 	"path"
+	"strings"
 	"sync"
 )
 
@@ -16,7 +17,9 @@ type Document struct {
 	BasePath           string                // Base for relative links
 	htmlMutex          *sync.Mutex           // Controls access to htmlNode
 	htmlNode           *html.Node            // Parsed output
-	hashMap            map[string]*html.Node // Map of valid id/names of nodes
+	hashMap            map[string]*html.Node // Map of ids to nodes, first wins
+	nameMap            map[string]*html.Node // Map of <a name> to nodes, first wins
+	mapMap             map[string]*html.Node // Map of <map> nodes by name
 	NodesOfInterest    []*html.Node          // Slice of nodes to run checks on
 	State              DocumentState         // Link to a DocumentState struct
 	DoctypeNode        *html.Node            // Pointer to doctype node if exists
@@ -26,7 +29,8 @@ type Document struct {
 // DocumentState struct, used by checks that depend on the document being
 // parsed.
 type DocumentState struct {
-	FaviconPresent bool // Have we found a favicon in the document?
+	FaviconPresent   bool            // Have we found a favicon in the document?
+	ImageMapsChecked map[string]bool // Names of <map>s whose areas are validated
 }
 
 // Init : Initialise the Document struct doesn't mesh nice with the NewXYZ()
@@ -37,6 +41,8 @@ func (doc *Document) Init() {
 	doc.htmlMutex = &sync.Mutex{}
 	doc.NodesOfInterest = make([]*html.Node, 0)
 	doc.hashMap = make(map[string]*html.Node)
+	doc.nameMap = make(map[string]*html.Node)
+	doc.mapMap = make(map[string]*html.Node)
 }
 
 // Parse : Ask Document to parse its HTML file. Returns quickly if this has
@@ -76,10 +82,19 @@ func (doc *Document) parseNode(n *html.Node) {
 	case html.DoctypeNode:
 		doc.DoctypeNode = n
 	case html.ElementNode:
-		// If present save fragment identifier to the hashMap
-		nodeID := GetID(n.Attr)
-		if nodeID != "" {
-			doc.hashMap[nodeID] = n
+		// If present save fragment identifiers, per the HTML spec any element's
+		// id but only an <a>'s name
+		if nodeID := GetID(n.Attr); nodeID != "" {
+			if _, ok := doc.hashMap[nodeID]; !ok {
+				doc.hashMap[nodeID] = n
+			}
+		}
+		if n.Data == "a" {
+			if name := GetAttr(n.Attr, "name"); name != "" {
+				if _, ok := doc.nameMap[name]; !ok {
+					doc.nameMap[name] = n
+				}
+			}
 		}
 		// Identify and store tags of interest
 		switch n.Data {
@@ -91,6 +106,12 @@ func (doc *Document) parseNode(n *html.Node) {
 		case "base":
 			// Set BasePath from <base> tag
 			doc.BasePath = path.Join(doc.BasePath, GetAttr(n.Attr, "href"))
+		case "map":
+			// Image maps are referenced by name, first one wins
+			name := GetAttr(n.Attr, "name")
+			if _, ok := doc.mapMap[name]; name != "" && !ok {
+				doc.mapMap[name] = n
+			}
 		}
 	case html.ErrorNode:
 		fmt.Printf("%+v\n", n)
@@ -105,9 +126,48 @@ func (doc *Document) parseNode(n *html.Node) {
 	}
 }
 
-// IsHashValid : Is a hash/fragment present in this Document.
+// IsHashValid : Is a hash/fragment present in this Document. As well as
+// elements, "top" is valid in any case as browsers scroll to the top for it.
 func (doc *Document) IsHashValid(hash string) bool {
+	if _, ok := doc.GetHashTarget(hash); ok {
+		return true
+	}
+	return strings.EqualFold(hash, "top")
+}
+
+// GetHashTarget : Get the element a hash/fragment scrolls to. The first
+// element with a matching id takes precedence over the first <a> with a
+// matching name.
+func (doc *Document) GetHashTarget(hash string) (*html.Node, bool) {
+	doc.Parse() // Ensure doc has been parsed
+	if n, ok := doc.hashMap[hash]; ok {
+		return n, true
+	}
+	n, ok := doc.nameMap[hash]
+	return n, ok
+}
+
+// GetMap : Get the <map> node with the given name, as referenced by usemap.
+func (doc *Document) GetMap(name string) (*html.Node, bool) {
+	doc.Parse() // Ensure doc has been parsed
+	n, ok := doc.mapMap[name]
+	return n, ok
+}
+
+// Walk : Call fn on every node in the Document, in document order, skipping
+// subtrees marked with the ignore attribute. Parses the document if needed.
+func (doc *Document) Walk(fn func(n *html.Node)) {
 	doc.Parse() // Ensure doc has been parsed
-	_, ok := doc.hashMap[hash]
-	return ok
+	doc.walkNode(doc.htmlNode, fn)
+}
+
+// Internal recursive function backing Walk.
+func (doc *Document) walkNode(n *html.Node, fn func(n *html.Node)) {
+	if doc.ignoreTagAttribute != "" && AttrPresent(n.Attr, doc.ignoreTagAttribute) {
+		return
+	}
+	fn(n)
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		doc.walkNode(c, fn)
+	}
 }
@@ -4,101 +4,283 @@
 package htmldoc
 
 import (
-	"github.com/wjdp/htmltest/output"
+	"fmt"
 	"os"
 	"path"
 	"regexp"
+	"sort"
+	"sync"
 )
 
+// Default number of directories read at once during discovery
+const defaultDiscoverConcurrency int = 32
+
 // DocumentStore struct, store of Documents including Document discovery
 type DocumentStore struct {
-	BasePath           string               // Path, relative to cwd, the site is located in
-	IgnorePatterns     []interface{}        // Regexes of directories to ignore
-	Documents          []*Document          // All of the documents, used to iterate over
-	DocumentPathMap    map[string]*Document // Maps slash separated paths to documents
-	DocumentExtension  string               // File extension to look for
-	DirectoryIndex     string               // What file is the index of the directory
-	IgnoreTagAttribute string               // Attribute to ignore element and children if found on element
+	BasePath            string                // Path, relative to cwd, the site is located in
+	IgnorePatterns      []interface{}         // Regexes of directories to ignore
+	IndexIgnored        bool                  // Still read ignored directories, indexing their files as assets
+	IgnoreFilePatterns  []interface{}         // Regexes of document paths to ignore
+	Documents           []*Document           // All of the documents, used to iterate over
+	DocumentPathMap     map[string]*Document  // Maps slash separated paths to documents
+	DocumentExtension   string                // File extension to look for
+	DirectoryIndex      string                // What file is the index of the directory
+	IgnoreTagAttribute  string                // Attribute to ignore element and children if found on element
+	DiscoverConcurrency int                   // Maximum directories read at once, defaults when zero
+	DiscoverErrors      []DiscoverError       // Paths that couldn't be read, complete once discovery is
+	ignoreRegexps       []*regexp.Regexp      // Compiled IgnorePatterns
+	ignoreFileRegexps   []*regexp.Regexp      // Compiled IgnoreFilePatterns
+	assets              map[string]assetEntry // Every file and directory under BasePath, see ResolveAsset
+	assetsLower         map[string][]string   // Paths in assets by their lowercase form
+	storeMutex          *sync.RWMutex         // Controls access to Documents, DocumentPathMap and discovering
+	storeCond           *sync.Cond            // Signalled when documents are added or discovery ends
+	discovering         bool                  // Is discovery running?
+}
+
+// DiscoverError : a path, or ignore pattern, discovery couldn't use.
+type DiscoverError struct {
+	Path string
+	Err  error
+}
+
+func (e DiscoverError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Err)
 }
 
 // NewDocumentStore : Create and return a new Document store.
 func NewDocumentStore() DocumentStore {
-	return DocumentStore{
+	dS := DocumentStore{
 		Documents:       make([]*Document, 0),
 		DocumentPathMap: make(map[string]*Document),
+		DiscoverErrors:  make([]DiscoverError, 0),
+		assets:          make(map[string]assetEntry),
+		assetsLower:     make(map[string][]string),
+		storeMutex:      &sync.RWMutex{},
 	}
+	dS.storeCond = sync.NewCond(dS.storeMutex)
+	return dS
 }
 
-// AddDocument : Add a document to the document store.
+// AddDocument : Add a document to the document store. Thread safe.
 func (dS *DocumentStore) AddDocument(doc *Document) {
+	// Pass some vars on
+	doc.ignoreTagAttribute = dS.IgnoreTagAttribute
 	// Save reference to document to various data stores
+	dS.storeMutex.Lock()
 	dS.Documents = append(dS.Documents, doc)
 	dS.DocumentPathMap[doc.SitePath] = doc
-	// Pass some vars on
-	doc.ignoreTagAttribute = dS.IgnoreTagAttribute
+	dS.storeCond.Broadcast()
+	dS.storeMutex.Unlock()
 }
 
-// Discover : Discover all documents within DocumentStore.BasePath.
+// Discover : Discover all documents within DocumentStore.BasePath, returns
+// once complete.
 func (dS *DocumentStore) Discover() {
-	dS.discoverRecurse(".")
+	dS.DiscoverAsync()
+	dS.WaitDiscovered()
 }
 
-// Does dir match one of the IgnorePatterns?
-func (dS *DocumentStore) isDirIgnored(dir string) bool {
-	for _, item := range dS.IgnorePatterns {
-		if ok, _ := regexp.MatchString(item.(string), dir+"/"); ok {
-			return true
+// discoverDir : a directory found during discovery, filled in once read
+type discoverDir struct {
+	documents []*Document    // Documents directly within, by name
+	subdirs   []*discoverDir // Directories directly within, by name
+	read      chan bool      // Closed once read, or given up on
+}
+
+// DiscoverAsync : Start discovering documents within DocumentStore.BasePath in
+// the background. Directories are read in parallel, documents are available
+// from DocumentAt as soon as they and those before them are found and
+// unreadable paths are saved to DiscoverErrors. Documents are always added in
+// the same order, each directory's own by name and then its subdirectories'.
+func (dS *DocumentStore) DiscoverAsync() {
+	dS.compileIgnorePatterns()
+
+	concurrency := dS.DiscoverConcurrency
+	if concurrency <= 0 {
+		concurrency = defaultDiscoverConcurrency
+	}
+
+	dS.storeMutex.Lock()
+	dS.discovering = true
+	dS.storeMutex.Unlock()
+
+	go func() {
+		// Buffered channel to act as concurrency limiter, only held while reading
+		readChannel := make(chan bool, concurrency)
+		var walk func(dir *discoverDir, dPath string, ignored bool)
+		walk = func(dir *discoverDir, dPath string, ignored bool) {
+			defer close(dir.read)
+			// Ignored directories hold no documents, they're only read to
+			// index their files as assets when asked to
+			ignored = ignored || dS.isDirIgnored(dPath)
+			if ignored && !dS.IndexIgnored {
+				dS.markUnindexed(dPath)
+				return
+			}
+
+			readChannel <- true
+			fis, err := readDir(path.Join(dS.BasePath, dPath))
+			<-readChannel
+			if err != nil {
+				if !ignored {
+					dS.addDiscoverError(dPath, err)
+				}
+				return
+			}
+
+			for _, fileinfo := range fis {
+				fPath := path.Join(dPath, fileinfo.Name())
+				entry := dS.indexAsset(fPath, fileinfo, ignored)
+				if entry.dir {
+					// If item is a dir, we delve deeper, symlinks aren't followed
+					if !entry.symlink {
+						subdir := &discoverDir{read: make(chan bool)}
+						dir.subdirs = append(dir.subdirs, subdir)
+						go walk(subdir, fPath, ignored)
+					}
+				} else if !ignored && path.Ext(fileinfo.Name()) == dS.DocumentExtension &&
+					!dS.isFileIgnored(fPath) {
+					// If a file, create and save document
+					newDoc := &Document{
+						FilePath: path.Join(dS.BasePath, fPath),
+						SitePath: fPath,
+						BasePath: dPath,
+					}
+					newDoc.Init()
+					dir.documents = append(dir.documents, newDoc)
+				}
+			}
 		}
+		// Add documents depth first as the directories holding them are read,
+		// returns once every directory has been
+		var add func(dir *discoverDir)
+		add = func(dir *discoverDir) {
+			<-dir.read
+			for _, doc := range dir.documents {
+				dS.AddDocument(doc)
+			}
+			for _, subdir := range dir.subdirs {
+				add(subdir)
+			}
+		}
+		root := &discoverDir{read: make(chan bool)}
+		go walk(root, ".", false)
+		add(root)
+
+		dS.storeMutex.Lock()
+		dS.discovering = false
+		dS.storeCond.Broadcast()
+		dS.storeMutex.Unlock()
+	}()
+}
+
+// WaitDiscovered : Block until any running discovery has finished.
+func (dS *DocumentStore) WaitDiscovered() {
+	dS.storeMutex.Lock()
+	for dS.discovering {
+		dS.storeCond.Wait()
 	}
-	return false
+	dS.storeMutex.Unlock()
 }
 
-// Recursive function to discover documents by walking the file tree
-func (dS *DocumentStore) discoverRecurse(dPath string) {
-	// Recurse over relative path dPath, saves found documents to dS
-	if dS.isDirIgnored(dPath) {
-		return
+// DocumentAt : Get the i-th discovered document, waiting for it to be found if
+// discovery is running. False once there are no more documents.
+func (dS *DocumentStore) DocumentAt(i int) (*Document, bool) {
+	dS.storeMutex.Lock()
+	defer dS.storeMutex.Unlock()
+	for i >= len(dS.Documents) && dS.discovering {
+		dS.storeCond.Wait()
 	}
+	if i < len(dS.Documents) {
+		return dS.Documents[i], true
+	}
+	return nil, false
+}
 
-	// Open directory to scan
-	f, err := os.Open(path.Join(dS.BasePath, dPath))
-	output.CheckErrorPanic(err)
+// Read the contents of a directory, sorted by name.
+func readDir(dirPath string) ([]os.FileInfo, error) {
+	f, err := os.Open(dirPath)
+	if err != nil {
+		return nil, err
+	}
 	defer f.Close()
+	fis, err := f.Readdir(-1)
+	sort.Slice(fis, func(i, j int) bool { return fis[i].Name() < fis[j].Name() })
+	return fis, err
+}
 
-	// Get FileInfo of directory (scan it)
-	fi, err := f.Stat()
-	output.CheckErrorPanic(err)
-
-	if fi.IsDir() { // Double check we're dealing with a directory
-		// Read all FileInfo-s from directory, Readdir(count int)
-		fis, err := f.Readdir(-1)
-		output.CheckErrorPanic(err)
-
-		// Iterate over contents of directory
-		for _, fileinfo := range fis {
-			fPath := path.Join(dPath, fileinfo.Name())
-			if fileinfo.IsDir() {
-				// If item is a dir, we delve deeper
-				dS.discoverRecurse(fPath)
-			} else if path.Ext(fileinfo.Name()) == dS.DocumentExtension {
-				// If a file, create and save document
-				newDoc := &Document{
-					FilePath: path.Join(dS.BasePath, fPath),
-					SitePath: fPath,
-					BasePath: dPath,
-				}
-				newDoc.Init()
-				dS.AddDocument(newDoc)
-			}
+// Save an error against a path, thread safe.
+func (dS *DocumentStore) addDiscoverError(p string, err error) {
+	dS.storeMutex.Lock()
+	dS.DiscoverErrors = append(dS.DiscoverErrors, DiscoverError{Path: p, Err: err})
+	dS.storeMutex.Unlock()
+}
+
+// Compile IgnorePatterns and IgnoreFilePatterns once, rather than for every
+// directory. Invalid patterns are saved to DiscoverErrors and otherwise
+// ignored.
+func (dS *DocumentStore) compileIgnorePatterns() {
+	dS.ignoreRegexps = dS.compilePatterns("IgnoreDirs", dS.IgnorePatterns)
+	dS.ignoreFileRegexps = dS.compilePatterns("IgnoreFiles", dS.IgnoreFilePatterns)
+}
+
+// Compile a list of patterns, name is used to describe invalid ones.
+func (dS *DocumentStore) compilePatterns(name string, patterns []interface{}) []*regexp.Regexp {
+	regexps := make([]*regexp.Regexp, 0, len(patterns))
+	for _, item := range patterns {
+		re, err := regexp.Compile(fmt.Sprintf("%v", item))
+		if err != nil {
+			dS.addDiscoverError(fmt.Sprintf("%s %q", name, item), err)
+			continue
+		}
+		regexps = append(regexps, re)
+	}
+	return regexps
+}
+
+// Does dir match one of the IgnorePatterns?
+func (dS *DocumentStore) isDirIgnored(dir string) bool {
+	for _, re := range dS.ignoreRegexps {
+		if re.MatchString(dir + "/") {
+			return true
 		}
-	} else { // It's a file, return single file
-		panic("discoverRecurse encountered a file: " + dPath)
 	}
+	return false
+}
 
+// Does the file at sitePath match one of the IgnoreFilePatterns?
+func (dS *DocumentStore) isFileIgnored(sitePath string) bool {
+	for _, re := range dS.ignoreFileRegexps {
+		if re.MatchString(sitePath) {
+			return true
+		}
+	}
+	return false
 }
 
-// ResolvePath : Resolves internal absolute paths to documents.
+// ResolvePath : Resolves internal absolute paths to documents. If discovery is
+// running and the path isn't found yet, waits for discovery to finish before
+// giving up.
 func (dS *DocumentStore) ResolvePath(refPath string) (*Document, bool) {
+	doc, ok := dS.resolvePath(refPath)
+	if ok {
+		return doc, ok
+	}
+	dS.storeMutex.RLock()
+	discovering := dS.discovering
+	dS.storeMutex.RUnlock()
+	if !discovering {
+		return doc, ok
+	}
+	dS.WaitDiscovered()
+	return dS.resolvePath(refPath)
+}
+
+// Single attempt at ResolvePath against the documents found so far.
+func (dS *DocumentStore) resolvePath(refPath string) (*Document, bool) {
+	dS.storeMutex.RLock()
+	defer dS.storeMutex.RUnlock()
+
 	// Match root document
 	if refPath == "/" {
 		d0, b0 := dS.DocumentPathMap[dS.DirectoryIndex]
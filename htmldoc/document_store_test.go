@@ -75,3 +75,82 @@ func TestDocumentStoreDocumentResolve(t *testing.T) {
 	_, b5 := dS.ResolvePath("does-not-exist")
 	assert.IsFalse(t, "does not return doc for invalid path", b5)
 }
+
+func TestDocumentStoreDiscoverAsync(t *testing.T) {
+	// documents are available from DocumentAt while discovery runs
+	dS := NewDocumentStore()
+	dS.BasePath = "fixtures/documents"
+	dS.DocumentExtension = ".html"
+	dS.DirectoryIndex = "index.html"
+	dS.DiscoverConcurrency = 1
+	dS.DiscoverAsync()
+	count := 0
+	for i := 0; ; i++ {
+		doc, ok := dS.DocumentAt(i)
+		if !ok {
+			break
+		}
+		assert.NotEquals(t, "document", doc, nil)
+		count++
+	}
+	assert.Equals(t, "document count", count, 6)
+	assert.Equals(t, "discover errors", len(dS.DiscoverErrors), 0)
+}
+
+func TestDocumentStoreDiscoverOrder(t *testing.T) {
+	// documents come in the same order every time, a directory's own by name
+	// then its subdirectories'
+	for run := 0; run < 5; run++ {
+		dS := NewDocumentStore()
+		dS.BasePath = "fixtures/documents"
+		dS.DocumentExtension = ".html"
+		dS.Discover()
+		paths := make([]string, 0, len(dS.Documents))
+		for _, doc := range dS.Documents {
+			paths = append(paths, doc.SitePath)
+		}
+		assert.StringEquals(t, "document order", paths, []string{"contact.html", "index.html",
+			"dir1/index.html", "dir1/dir11/index.html", "dir2/index.html", "lib/unwanted-file.html"})
+	}
+}
+
+func TestDocumentStoreResolveWhileDiscovering(t *testing.T) {
+	// ResolvePath waits for discovery rather than missing a document
+	dS := NewDocumentStore()
+	dS.BasePath = "fixtures/documents"
+	dS.DocumentExtension = ".html"
+	dS.DirectoryIndex = "index.html"
+	dS.DiscoverAsync()
+	d, b := dS.ResolvePath("dir2/index.html")
+	assert.IsTrue(t, "dir2/index.html exists", b)
+	assert.Equals(t, "dir2/index.html resolves to correct document",
+		d.FilePath, "fixtures/documents/dir2/index.html")
+	dS.WaitDiscovered()
+}
+
+func TestDocumentStoreDiscoverErrors(t *testing.T) {
+	// unreadable paths and invalid patterns are reported, not panicked on
+	dS := NewDocumentStore()
+	dS.BasePath = "fixtures/does-not-exist"
+	dS.DocumentExtension = ".html"
+	dS.DirectoryIndex = "index.html"
+	dS.IgnorePatterns = []interface{}{"["}
+	dS.Discover()
+	assert.Equals(t, "document count", len(dS.Documents), 0)
+	assert.Equals(t, "discover errors", len(dS.DiscoverErrors), 2)
+}
+
+func TestDocumentStoreIgnoreFilePatterns(t *testing.T) {
+	// documents matching IgnoreFilePatterns aren't discovered
+	dS := NewDocumentStore()
+	dS.BasePath = "fixtures/documents"
+	dS.DocumentExtension = ".html"
+	dS.DirectoryIndex = "index.html"
+	dS.IgnoreFilePatterns = []interface{}{"^contact\\.html$", "dir1/index"}
+	dS.Discover()
+	assert.Equals(t, "document count", len(dS.Documents), 4)
+	_, ok := dS.DocumentPathMap["contact.html"]
+	assert.IsFalse(t, "contact.html ignored", ok)
+	_, exists := dS.ResolveAsset("contact.html")
+	assert.IsTrue(t, "contact.html still an asset", exists)
+}
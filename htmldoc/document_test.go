@@ -2,6 +2,7 @@ package htmldoc
 
 import (
 	"github.com/daviddengcn/go-assert"
+	"golang.org/x/net/html"
 	"sync"
 	"testing"
 )
@@ -89,6 +90,59 @@ func TestDocumentIsHashValid(t *testing.T) {
 	doc.Parse()
 
 	assert.IsTrue(t, "#xyz present", doc.IsHashValid("xyz"))
-	assert.IsTrue(t, "#prq present", doc.IsHashValid("prq"))
+	// name only counts on <a>
+	assert.IsFalse(t, "#prq present", doc.IsHashValid("prq"))
 	assert.IsFalse(t, "#abc present", doc.IsHashValid("abc"))
 }
+
+func TestDocumentHashTarget(t *testing.T) {
+	// fragments resolve per the HTML spec
+	doc := Document{
+		FilePath: "fixtures/documents/fragments.htm",
+	}
+	doc.Init()
+	doc.Parse()
+
+	n, ok := doc.GetHashTarget("both")
+	assert.IsTrue(t, "#both present", ok)
+	assert.Equals(t, "id wins over earlier name", n.Data, "h2")
+	n, ok = doc.GetHashTarget("dup")
+	assert.IsTrue(t, "#dup present", ok)
+	assert.Equals(t, "first id wins", GetAttr(n.Attr, "class"), "first")
+	_, ok = doc.GetHashTarget("legacy")
+	assert.IsTrue(t, "#legacy <a name> present", ok)
+	_, ok = doc.GetHashTarget("y")
+	assert.IsTrue(t, "#y id of <a name=x> present", ok)
+	assert.IsTrue(t, "#x name of <a id=y> present", doc.IsHashValid("x"))
+	assert.IsFalse(t, "#q <input name> absent", doc.IsHashValid("q"))
+	assert.IsTrue(t, "#top always valid", doc.IsHashValid("top"))
+	assert.IsTrue(t, "#TOP always valid", doc.IsHashValid("TOP"))
+}
+
+func TestDocumentWalk(t *testing.T) {
+	// walk visits every node, skipping ignored subtrees
+	doc := Document{
+		FilePath:           "fixtures/documents/walk.htm",
+		ignoreTagAttribute: "data-proofer-ignore",
+	}
+	doc.Init()
+	elements := 0
+	doc.Walk(func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "a" {
+			elements++
+		}
+	})
+	assert.Equals(t, "anchors walked", elements, 2)
+}
+
+func TestDocumentGetMap(t *testing.T) {
+	// maps are found by name, not id
+	doc := Document{
+		FilePath: "fixtures/documents/maps.htm",
+	}
+	doc.Init()
+	_, b1 := doc.GetMap("primary")
+	assert.IsTrue(t, "map primary present", b1)
+	_, b2 := doc.GetMap("by-id")
+	assert.IsFalse(t, "map by id absent", b2)
+}
@@ -14,6 +14,7 @@ type Reference struct {
 	Node     *html.Node // Node reference was created from
 	Path     string     // href/src taken verbatim from source
 	URL      *url.URL   // URL object created from Path
+	Field    string     // Where in a data file Path came from, empty for HTML
 }
 
 // NewReference : Create a new reference given a document, node and path.
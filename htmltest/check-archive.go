@@ -0,0 +1,109 @@
+package htmltest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/wjdp/htmltest/issues"
+)
+
+// archiveAvailability : response of a Wayback Machine compatible
+// availability API, see https://archive.org/help/wayback_api.php
+type archiveAvailability struct {
+	ArchivedSnapshots struct {
+		Closest *struct {
+			Available bool   `json:"available"`
+			URL       string `json:"url"`
+			Status    string `json:"status"`
+		} `json:"closest"`
+	} `json:"archived_snapshots"`
+}
+
+// Find the closest archived copy of a failing external URL to suggest as a
+// replacement, if SuggestArchivedLinks is enabled. Lookups, including misses,
+// are cached. Returns an empty string when there's no usable snapshot.
+func (hT *HTMLTest) archivedSnapshot(urlStr string) string {
+	if !hT.opts.SuggestArchivedLinks {
+		return ""
+	}
+	if snapshot, ok := hT.refCache.GetArchive(urlStr); ok {
+		return snapshot
+	}
+
+	snapshot, err := hT.archiveLookup(urlStr)
+	if err != nil {
+		// Don't cache failed lookups, the API may be back next time
+		hT.issueStore.AddIssue(issues.Issue{
+			Level:   issues.LevelDebug,
+			Message: "archive lookup failed for " + urlStr + ": " + err.Error(),
+		})
+		return ""
+	}
+	hT.refCache.SaveArchive(urlStr, snapshot)
+	return snapshot
+}
+
+// Is a failure permanent, so worth suggesting an archived copy for? Only
+// 404 and 410 statuses and hosts DNS doesn't know, not timeouts, refused
+// connections or statuses which may pass next time, such as 5xx and 429.
+func isDeadLink(statusCode int, err error) bool {
+	if err != nil {
+		return strings.Contains(err.Error(), "no such host")
+	}
+	return statusCode == http.StatusNotFound || statusCode == http.StatusGone
+}
+
+// An archived copy of urlStr if its failure, a status or a client error, is
+// permanent.
+func (hT *HTMLTest) deadLinkSnapshot(urlStr string, statusCode int, err error) string {
+	if !isDeadLink(statusCode, err) {
+		return ""
+	}
+	return hT.archivedSnapshot(urlStr)
+}
+
+// Query the availability API for urlStr.
+func (hT *HTMLTest) archiveLookup(urlStr string) (string, error) {
+	apiURL, err := url.Parse(hT.opts.ArchiveAvailabilityURL)
+	if err != nil {
+		return "", err
+	}
+	query := apiURL.Query()
+	query.Set("url", urlStr)
+	apiURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequest("GET", apiURL.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", "htmltest/"+hT.opts.Version)
+
+	hT.httpChannel <- true // Add to http concurrency limiter
+	resp, err := hT.httpClient.Do(req)
+	<-hT.httpChannel // Bump off http concurrency limiter
+	if err != nil {
+		return "", err
+	}
+	defer hT.closeBody(resp)
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("availability API status %d", resp.StatusCode)
+	}
+
+	var availability archiveAvailability
+	if err := json.NewDecoder(resp.Body).Decode(&availability); err != nil {
+		return "", err
+	}
+	closest := availability.ArchivedSnapshots.Closest
+	if closest == nil || !closest.Available {
+		return "", nil
+	}
+	// Snapshots of error pages are no use as a replacement
+	if status, err := strconv.Atoi(closest.Status); err == nil && !statusCodeValid(status) {
+		return "", nil
+	}
+	return closest.URL, nil
+}
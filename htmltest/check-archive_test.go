@@ -0,0 +1,152 @@
+package htmltest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/daviddengcn/go-assert"
+)
+
+// Server acting as both a site with dead links and an availability API.
+// /gone has a snapshot, /lost has none and /error only has a snapshot of an
+// error page. /busy and /slow-down fail for now. API calls are counted.
+func tArchiveServer(apiCalls *int) *httptest.Server {
+	var mutex sync.Mutex
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/wayback/available" {
+			switch r.URL.Path {
+			case "/ok":
+				w.WriteHeader(http.StatusOK)
+			case "/busy":
+				w.WriteHeader(http.StatusServiceUnavailable)
+			case "/slow-down":
+				w.WriteHeader(http.StatusTooManyRequests)
+			case "/removed":
+				w.WriteHeader(http.StatusGone)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+			return
+		}
+		mutex.Lock()
+		*apiCalls++
+		mutex.Unlock()
+		target := r.URL.Query().Get("url")
+		switch {
+		case strings.HasSuffix(target, "/gone"):
+			fmt.Fprintf(w, `{"url": %q, "archived_snapshots": {"closest": {"available": true,
+				"url": "http://web.archive.org/web/20200101000000/%s", "timestamp": "20200101000000",
+				"status": "200"}}}`, target, target)
+		case strings.HasSuffix(target, "/error"):
+			fmt.Fprintf(w, `{"url": %q, "archived_snapshots": {"closest": {"available": true,
+				"url": "http://web.archive.org/web/20200101000000/%s", "timestamp": "20200101000000",
+				"status": "404"}}}`, target, target)
+		default:
+			fmt.Fprintf(w, `{"url": %q, "archived_snapshots": {}}`, target)
+		}
+	}))
+}
+
+func TestArchiveDefault(t *testing.T) {
+	// doesn't look up snapshots by default
+	apiCalls := 0
+	server := tArchiveServer(&apiCalls)
+	defer server.Close()
+	hT := tTestExternalLinks(server.URL, []string{"/gone"},
+		map[string]interface{}{"ArchiveAvailabilityURL": server.URL + "/wayback/available"})
+	tExpectIssueCount(t, hT, 1)
+	tExpectIssue(t, hT, "suggest", 0)
+	assert.Equals(t, "api calls", apiCalls, 0)
+}
+
+func TestArchiveSuggestion(t *testing.T) {
+	// the closest snapshot is attached to failing links
+	apiCalls := 0
+	server := tArchiveServer(&apiCalls)
+	defer server.Close()
+	hT := tTestExternalLinks(server.URL, []string{"/gone", "/lost", "/error", "/ok"},
+		map[string]interface{}{
+			"SuggestArchivedLinks":   true,
+			"ArchiveAvailabilityURL": server.URL + "/wayback/available",
+		})
+	tExpectIssueCount(t, hT, 3)
+	assert.Equals(t, "api calls", apiCalls, 3)
+	snapshot, ok := hT.refCache.GetArchive(server.URL + "/gone")
+	assert.IsTrue(t, "lookup cached", ok)
+	assert.Equals(t, "snapshot", snapshot, "http://web.archive.org/web/20200101000000/"+server.URL+"/gone")
+	snapshot, ok = hT.refCache.GetArchive(server.URL + "/lost")
+	assert.IsTrue(t, "miss cached", ok)
+	assert.Equals(t, "no snapshot", snapshot, "")
+	snapshot, _ = hT.refCache.GetArchive(server.URL + "/error")
+	assert.Equals(t, "error page snapshot ignored", snapshot, "")
+}
+
+func TestArchiveSuggestionCached(t *testing.T) {
+	// each dead URL is only looked up once
+	apiCalls := 0
+	server := tArchiveServer(&apiCalls)
+	defer server.Close()
+	hT := tTestExternalLinks(server.URL, []string{"/gone", "/gone?again", "/gone"},
+		map[string]interface{}{
+			"SuggestArchivedLinks":   true,
+			"ArchiveAvailabilityURL": server.URL + "/wayback/available",
+			"StripQueryString":       true,
+		})
+	tExpectIssueCount(t, hT, 3)
+	assert.Equals(t, "api calls", apiCalls, 1)
+}
+
+func TestArchiveOnlyDeadLinks(t *testing.T) {
+	// transient failures aren't looked up, gone ones are
+	apiCalls := 0
+	server := tArchiveServer(&apiCalls)
+	defer server.Close()
+	hT := tTestExternalLinks(server.URL, []string{"/busy", "/slow-down", "/removed"},
+		map[string]interface{}{
+			"SuggestArchivedLinks":   true,
+			"ArchiveAvailabilityURL": server.URL + "/wayback/available",
+		})
+	// 429 is reported as blocked for bots, a warning
+	tExpectIssueCount(t, hT, 2)
+	assert.Equals(t, "api calls", apiCalls, 1)
+	_, ok := hT.refCache.GetArchive(server.URL + "/busy")
+	assert.IsFalse(t, "503 not looked up", ok)
+	_, ok = hT.refCache.GetArchive(server.URL + "/removed")
+	assert.IsTrue(t, "410 looked up", ok)
+}
+
+func TestArchiveOnlyDeadHosts(t *testing.T) {
+	// refused connections aren't looked up, missing hosts are
+	assert.IsFalse(t, "refused", isDeadLink(0, errors.New("Get http://x: dial tcp 127.0.0.1:1: connect: connection refused")))
+	assert.IsFalse(t, "timeout", isDeadLink(0, errors.New("Get http://x: net/http: request canceled (Client.Timeout exceeded)")))
+	assert.IsTrue(t, "no such host", isDeadLink(0, errors.New("Get http://x: dial tcp: lookup x: no such host")))
+	assert.IsTrue(t, "404", isDeadLink(http.StatusNotFound, nil))
+	assert.IsFalse(t, "500", isDeadLink(http.StatusInternalServerError, nil))
+}
+
+func TestArchiveAPIDown(t *testing.T) {
+	// a failing API doesn't change the issue
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/wayback/available" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+	hT := tTestExternalLinks(server.URL, []string{"/gone"},
+		map[string]interface{}{
+			"SuggestArchivedLinks":   true,
+			"ArchiveAvailabilityURL": server.URL + "/wayback/available",
+		})
+	tExpectIssueCount(t, hT, 1)
+	tExpectIssue(t, hT, "Non-OK status: 404", 1)
+	tExpectIssue(t, hT, "archive lookup failed", 1)
+	_, ok := hT.refCache.GetArchive(server.URL + "/gone")
+	assert.IsFalse(t, "failure not cached", ok)
+}
@@ -0,0 +1,104 @@
+package htmltest
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/wjdp/htmltest/htmldoc"
+	"github.com/wjdp/htmltest/issues"
+	"github.com/wjdp/htmltest/output"
+)
+
+// Header profiles external requests can be made with, the working profile is
+// remembered per host in the refcache.
+const (
+	profileDefault string = ""        // htmltest User-Agent plus HTTPHeaders
+	profileBrowser string = "browser" // As profileDefault, overridden to look like a desktop browser
+)
+
+// Headers sent by profileBrowser, on top of HTTPHeaders
+var browserProfileHeaders = map[string]string{
+	"User-Agent": "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 " +
+		"(KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+	"Accept":                    "text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,*/*;q=0.8",
+	"Accept-Language":           "en-US,en;q=0.9",
+	"Upgrade-Insecure-Requests": "1",
+	"Sec-Fetch-Dest":            "document",
+	"Sec-Fetch-Mode":            "navigate",
+	"Sec-Fetch-Site":            "none",
+}
+
+// Make a GET request to urlStr with the given header profile, respecting the
+// http concurrency limit.
+func (hT *HTMLTest) doExternal(urlStr string, profile string) (*http.Response, error) {
+	req := hT.newExternalRequest(urlStr, profile)
+	hT.httpChannel <- true // Add to http concurrency limiter
+	var resp *http.Response
+	var err error
+	if hT.traceExternal != nil {
+		resp, err = hT.traceExternal(req)
+	} else {
+		resp, err = hT.httpClient.Do(req)
+	}
+	<-hT.httpChannel // Bump off http concurrency limiter
+	return resp, err
+}
+
+// Build a GET request to urlStr with the headers of the given profile.
+func (hT *HTMLTest) newExternalRequest(urlStr string, profile string) *http.Request {
+	req, err := http.NewRequest("GET", urlStr, nil)
+	// Only error NewRequest raises is if the url isn't valid, we have already checked it by this point so OK just
+	// to panic if err != nil.
+	output.CheckErrorPanic(err)
+
+	// Set UA header
+	req.Header.Set("User-Agent", "htmltest/"+hT.opts.Version)
+
+	// Set headers from HTTPHeaders option
+	for key, value := range hT.opts.HTTPHeaders {
+		// Due to the way we're loading in config these keys and values are interface{}. In normal cases they are
+		// strings, but could very easily be ints (side note: this isn't great, we'll fix this later, #73)
+		req.Header.Set(fmt.Sprintf("%v", key), fmt.Sprintf("%v", value))
+	}
+
+	if profile == profileBrowser {
+		for key, value := range browserProfileHeaders {
+			req.Header.Set(key, value)
+		}
+	}
+	return req
+}
+
+// Is statusCode one of BotBlockStatuses?
+func (hT *HTMLTest) isBotBlockStatus(statusCode int) bool {
+	for _, item := range hT.opts.BotBlockStatuses {
+		if fmt.Sprint(item) == fmt.Sprint(statusCode) {
+			return true
+		}
+	}
+	return false
+}
+
+// Retry a request which looks to have been blocked for being a bot with
+// profileBrowser. If that gets through the profile is saved against the host.
+// Returns the response to use and whether it's still blocked.
+func (hT *HTMLTest) retryAsBrowser(ref *htmldoc.Reference, urlStr string, blocked *http.Response) (*http.Response, bool) {
+	hT.issueStore.AddIssue(issues.Issue{
+		Level:     issues.LevelInfo,
+		Message:   fmt.Sprintf("status %d, retrying as a browser", blocked.StatusCode),
+		Reference: ref,
+	})
+
+	resp, err := hT.doExternal(urlStr, profileBrowser)
+	if err != nil {
+		return blocked, true
+	}
+	hT.closeBody(blocked)
+	if hT.isBotBlockStatus(resp.StatusCode) {
+		return resp, true
+	}
+	if statusCodeValid(resp.StatusCode) {
+		hT.refCache.SaveHostProfile(ref.URL.Host, profileBrowser)
+	}
+	return resp, false
+}
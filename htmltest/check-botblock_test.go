@@ -0,0 +1,99 @@
+package htmltest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/daviddengcn/go-assert"
+)
+
+// Server which turns away clients that don't look like browsers. /picky lets
+// browsers through, /wall blocks everyone and /gone doesn't exist. Requests
+// made with the htmltest User-Agent are counted.
+func tBotServer(botRequests *int) *httptest.Server {
+	var mutex sync.Mutex
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		isBrowser := strings.HasPrefix(r.Header.Get("User-Agent"), "Mozilla/")
+		if !isBrowser {
+			mutex.Lock()
+			*botRequests++
+			mutex.Unlock()
+		}
+		switch {
+		case r.URL.Path == "/gone":
+			w.WriteHeader(http.StatusNotFound)
+		case strings.HasPrefix(r.URL.Path, "/picky") && isBrowser:
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+}
+
+func TestBotBlockRetryAsBrowser(t *testing.T) {
+	// links blocked for bots but fine for browsers pass
+	botRequests := 0
+	server := tBotServer(&botRequests)
+	defer server.Close()
+	hT := tTestExternalLinks(server.URL, []string{"/picky/1"}, map[string]interface{}{})
+	tExpectIssueCount(t, hT, 0)
+	tExpectIssue(t, hT, "blocked for bots", 0)
+	assert.Equals(t, "host profile", hT.refCache.GetHostProfile(strings.TrimPrefix(server.URL, "http://")), profileBrowser)
+}
+
+func TestBotBlockProfileRemembered(t *testing.T) {
+	// once the browser profile works for a host it's used straight away
+	botRequests := 0
+	server := tBotServer(&botRequests)
+	defer server.Close()
+	hT := tTestExternalLinks(server.URL, []string{"/picky/1", "/picky/2", "/picky/3"},
+		map[string]interface{}{})
+	tExpectIssueCount(t, hT, 0)
+	assert.Equals(t, "bot requests", botRequests, 1)
+}
+
+func TestBotBlocked(t *testing.T) {
+	// links blocked for everyone are reported as blocked, not broken
+	botRequests := 0
+	server := tBotServer(&botRequests)
+	defer server.Close()
+	hT := tTestExternalLinks(server.URL, []string{"/wall"}, map[string]interface{}{})
+	tExpectIssueCount(t, hT, 0)
+	tExpectIssue(t, hT, "blocked for bots, status 403", 1)
+	tExpectIssue(t, hT, "Non-OK status", 0)
+}
+
+func TestBotBlockedLevel(t *testing.T) {
+	// the level of blocked issues is configurable
+	botRequests := 0
+	server := tBotServer(&botRequests)
+	defer server.Close()
+	hT := tTestExternalLinks(server.URL, []string{"/wall"}, map[string]interface{}{"BotBlockedLevel": 3})
+	tExpectIssueCount(t, hT, 1)
+	tExpectIssue(t, hT, "blocked for bots, status 403", 1)
+}
+
+func TestBotBlockStatusesEmpty(t *testing.T) {
+	// detection can be disabled, blocked links are then broken
+	botRequests := 0
+	server := tBotServer(&botRequests)
+	defer server.Close()
+	hT := tTestExternalLinks(server.URL, []string{"/picky/1"},
+		map[string]interface{}{"BotBlockStatuses": []interface{}{}})
+	tExpectIssueCount(t, hT, 1)
+	tExpectIssue(t, hT, "Non-OK status: 403", 1)
+}
+
+func TestBotBlockOtherStatus(t *testing.T) {
+	// other failures aren't retried
+	botRequests := 0
+	server := tBotServer(&botRequests)
+	defer server.Close()
+	hT := tTestExternalLinks(server.URL, []string{"/gone"}, map[string]interface{}{})
+	tExpectIssueCount(t, hT, 1)
+	tExpectIssue(t, hT, "Non-OK status: 404", 1)
+	tExpectIssue(t, hT, "retrying as a browser", 0)
+}
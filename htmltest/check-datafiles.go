@@ -0,0 +1,198 @@
+package htmltest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/wjdp/htmltest/htmldoc"
+	"github.com/wjdp/htmltest/issues"
+	"golang.org/x/net/html"
+	"gopkg.in/yaml.v2"
+)
+
+// dataFileRule : a DataFiles entry, files matching glob have the URLs found
+// by each of fields checked
+type dataFileRule struct {
+	glob       string
+	globRegexp *regexp.Regexp
+	fields     []string
+	steps      [][]dataPathStep
+}
+
+// Stands in for the element references in data files hang off, checks expect
+// one to be present
+var dataFileNode = &html.Node{Type: html.ElementNode, Data: "datafile"}
+
+// Describes the files checkEmbeddedURL is given URLs from, by stand in node
+var embeddedNames = map[string]string{
+	"datafile": "data file",
+	"pdf":      "PDF",
+}
+
+// Convert a glob to a regexp matching whole site paths. * and ? don't cross
+// directories, ** does and **/ may match no directories at all.
+func globToRegexp(glob string) *regexp.Regexp {
+	glob = strings.TrimPrefix(glob, "/")
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case strings.HasPrefix(glob[i:], "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.MustCompile(sb.String())
+}
+
+// Site level check, run once all documents have been tested. Extracts URLs
+// from data files matching DataFiles and checks them as links, issues are
+// attributed to the data file and the field the URL was found in.
+func (hT *HTMLTest) checkDataFiles() {
+	if len(hT.dataFileRules) == 0 {
+		return
+	}
+
+	files := hT.documentStore.AssetFiles()
+	for _, rule := range hT.dataFileRules {
+		matched := make([]bool, len(rule.fields))
+		for _, sitePath := range files {
+			if !rule.globRegexp.MatchString(sitePath) {
+				continue
+			}
+			if hT.opts.OnlyFailed && !hT.previousFailures.hasDocument(sitePath) {
+				// Still counts, it had its chance last run
+				for i := range matched {
+					matched[i] = true
+				}
+				continue
+			}
+			hT.checkDataFile(sitePath, rule, matched)
+		}
+		// Most likely a typo in the selector
+		for i, ok := range matched {
+			if !ok {
+				hT.issueStore.AddIssue(issues.Issue{
+					Level:   issues.LevelWarning,
+					Message: fmt.Sprintf("data field %s matched nothing in %s", rule.fields[i], rule.glob),
+					Check:   "Data files",
+				})
+			}
+		}
+	}
+}
+
+// Check the URLs fields of rule select in a single data file, marking
+// selectors which found something in matched.
+func (hT *HTMLTest) checkDataFile(sitePath string, rule dataFileRule, matched []bool) {
+	document := &htmldoc.Document{
+		FilePath: path.Join(hT.documentStore.BasePath, sitePath),
+		SitePath: sitePath,
+		BasePath: path.Dir(sitePath),
+	}
+	document.Init()
+	defer hT.issueStore.PrintDocumentIssues(document)
+
+	hT.issueStore.AddIssue(issues.Issue{
+		Level:   issues.LevelDebug,
+		Message: "checkDataFile on " + sitePath,
+		Check:   "Data files",
+	})
+
+	data, err := readDataFile(document.FilePath)
+	if err != nil {
+		hT.issueStore.AddIssue(issues.Issue{
+			Level:    issues.LevelError,
+			Document: document,
+			Message:  fmt.Sprintf("cannot parse data file: %s", err),
+			Check:    "Data files",
+		})
+		return
+	}
+
+	for i, steps := range rule.steps {
+		for _, m := range selectDataPath(data, steps) {
+			urlStr, ok := m.value.(string)
+			if !ok {
+				continue
+			}
+			matched[i] = true
+			hT.checkEmbeddedURL("Data files", document, dataFileNode, m.field, strings.TrimSpace(urlStr))
+		}
+	}
+}
+
+// Read a JSON or YAML file, chosen by extension, into the types JSON decodes
+// to.
+func readDataFile(filePath string) (interface{}, error) {
+	b, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	var data interface{}
+	switch strings.ToLower(path.Ext(filePath)) {
+	case ".yml", ".yaml":
+		err = yaml.Unmarshal(b, &data)
+		data = normaliseData(data)
+	default:
+		err = json.Unmarshal(b, &data)
+	}
+	return data, err
+}
+
+// Route a URL found at field in a data file or PDF through the same checks as
+// a link href. node is the stand in element for the kind of file, check names
+// the check its own issues are grouped under.
+func (hT *HTMLTest) checkEmbeddedURL(check string, document *htmldoc.Document, node *html.Node, field string,
+	urlStr string) {
+	if urlStr == "" {
+		return
+	}
+	ref, err := htmldoc.NewReference(document, node, urlStr)
+	if err != nil {
+		hT.issueStore.AddIssue(issues.Issue{
+			Level:    issues.LevelError,
+			Document: document,
+			Message:  fmt.Sprintf("bad reference at %s: %q", field, err),
+			Check:    check,
+		})
+		return
+	}
+	ref.Field = field
+
+	switch ref.Scheme() {
+	case "http":
+		hT.enforceHTTPS(ref)
+		hT.checkExternal(ref)
+	case "https":
+		hT.checkExternal(ref)
+	case "file":
+		hT.checkInternal(ref)
+	case "mailto":
+		hT.checkMailto(ref)
+	case "tel":
+		hT.checkTel(ref)
+	default:
+		// Fragments only, or values which happen to match but aren't URLs
+		hT.issueStore.AddIssue(issues.Issue{
+			Level:     issues.LevelDebug,
+			Message:   "not checked in " + embeddedNames[node.Data],
+			Reference: ref,
+			Check:     check,
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package htmltest
+
+import (
+	"testing"
+
+	"github.com/daviddengcn/go-assert"
+	"github.com/wjdp/htmltest/issues"
+)
+
+// DataFiles covering the JSON and YAML fixtures
+func tDataFilesOpts() map[string]interface{} {
+	return map[string]interface{}{
+		"DataFiles": []interface{}{
+			map[interface{}]interface{}{
+				"Glob":   "data/*.json",
+				"Fields": []interface{}{"$.items[*].url", "contact"},
+			},
+			map[interface{}]interface{}{
+				"Glob":   "**/*.yml",
+				"Fields": []interface{}{"menu[*].link", "$..href"},
+			},
+		},
+	}
+}
+
+func TestDataFilesDisabledByDefault(t *testing.T) {
+	// data files aren't read unless configured
+	hT := tTestDirectory("fixtures/datafiles")
+	tExpectIssueCount(t, hT, 0)
+}
+
+func TestDataFilesBroken(t *testing.T) {
+	// internal urls in JSON and YAML are checked like links
+	hT := tTestDirectoryOpts("fixtures/datafiles", tDataFilesOpts())
+	tExpectIssueCount(t, hT, 4)
+	tExpectIssue(t, hT, "target does not exist", 2)
+	tExpectIssue(t, hT, "hash does not exist", 1)
+	tExpectIssue(t, hT, "cannot parse data file", 1)
+}
+
+func TestDataFilesAttribution(t *testing.T) {
+	// issues name the data file and the field the url came from
+	found := make(map[string]string)
+	hooks := Hooks{IssueAdded: func(issue issues.Issue) {
+		if issue.Level == issues.LevelError && issue.Reference != nil {
+			found[issue.Reference.Path] = issue.Reference.Document.SitePath + " " + issue.Reference.Field
+		}
+	}}
+	opts := defaultDirectoryTestOpts("fixtures/datafiles")
+	for k, v := range tDataFilesOpts() {
+		opts[k] = v
+	}
+	_, err := TestWithHooks(opts, hooks)
+	assert.Equals(t, "error", err, nil)
+	assert.Equals(t, "json", found["/gone.html"], "data/links.json $.items[2].url")
+	assert.Equals(t, "yaml", found["../missing/"], "data/nav.yml $.menu[1].link")
+	assert.Equals(t, "recursive", found["/docs/#nowhere"], "data/nav.yml $.footer.links[0].href")
+}
+
+func TestDataFilesUnmatchedField(t *testing.T) {
+	// a selector finding nothing is probably a typo
+	hT := tTestDirectoryOpts("fixtures/datafiles", map[string]interface{}{
+		"DataFiles": []interface{}{
+			map[string]interface{}{"Glob": "data/links.json", "Fields": "$.items[*].link"},
+		},
+	})
+	tExpectIssueCount(t, hT, 0)
+	tExpectIssue(t, hT, "data field $.items[*].link matched nothing in data/links.json", 1)
+}
+
+func TestDataFilesBadConfig(t *testing.T) {
+	// rules are validated before testing starts
+	opts := defaultDirectoryTestOpts("fixtures/datafiles")
+	opts["DataFiles"] = []interface{}{map[string]interface{}{"Glob": "*.json"}}
+	_, err := Test(opts)
+	assert.NotEquals(t, "missing fields", err, nil)
+
+	opts["DataFiles"] = []interface{}{map[string]interface{}{"Glob": "*.json", "Fields": "$.items["}}
+	_, err = Test(opts)
+	assert.NotEquals(t, "bad selector", err, nil)
+
+	opts["DataFiles"] = []interface{}{"*.json"}
+	_, err = Test(opts)
+	assert.NotEquals(t, "not a map", err, nil)
+}
+
+func TestGlobToRegexp(t *testing.T) {
+	cases := []struct {
+		glob  string
+		path  string
+		match bool
+	}{
+		{"data/*.json", "data/links.json", true},
+		{"data/*.json", "data/sub/links.json", false},
+		{"**/*.yml", "nav.yml", true},
+		{"**/*.yml", "data/deep/nav.yml", true},
+		{"/data/**", "data/a/b.json", true},
+		{"data/?.json", "data/ab.json", false},
+		{"a.json", "axjson", false},
+	}
+	for _, c := range cases {
+		assert.Equals(t, c.glob+" "+c.path, globToRegexp(c.glob).MatchString(c.path), c.match)
+	}
+}
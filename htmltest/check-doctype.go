@@ -15,6 +15,7 @@ func (hT *HTMLTest) checkDoctype(document *htmldoc.Document) {
 			Level:    issues.LevelError,
 			Message:  "missing doctype",
 			Document: document,
+			Check:    "Doctype",
 		})
 		return
 	}
@@ -25,6 +26,7 @@ func (hT *HTMLTest) checkDoctype(document *htmldoc.Document) {
 		Message: fmt.Sprintf("DOCTYPE %+v %+v\n",
 			document.DoctypeNode.Data, document.DoctypeNode.Attr),
 		Document: document,
+		Check:    "Doctype",
 	})
 
 	isHTML5 := (document.DoctypeNode.Data == "html" &&
@@ -35,6 +37,7 @@ func (hT *HTMLTest) checkDoctype(document *htmldoc.Document) {
 			Level:    issues.LevelError,
 			Message:  "doctype isn't html5",
 			Document: document,
+			Check:    "Doctype",
 		})
 	}
 
@@ -0,0 +1,92 @@
+package htmltest
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/wjdp/htmltest/htmldoc"
+	"github.com/wjdp/htmltest/issues"
+	"golang.org/x/net/html"
+)
+
+// Profiles, set by the Profile option. Email templates are read by mail
+// clients with no base URL, no scripting and little CSS support.
+const (
+	profileWeb   = "web"
+	profileEmail = "email"
+)
+
+// Under the email profile, reports references emails can't resolve:
+// relative and protocol relative ones. Returns true if the reference was
+// handled and shouldn't be routed further. URLs matching IgnoreURLs, such as
+// template merge tags, are skipped.
+func (hT *HTMLTest) checkEmailRelative(ref *htmldoc.Reference) bool {
+	if hT.opts.Profile != profileEmail {
+		return false
+	}
+	if hT.opts.isURLIgnored(ref.Path) {
+		return true
+	}
+
+	if strings.HasPrefix(ref.Path, "//") {
+		hT.issueStore.AddIssue(issues.Issue{
+			Level:     issues.LevelError,
+			Message:   "protocol relative reference, emails need absolute https URLs",
+			Reference: ref,
+			Check:     "Email",
+		})
+		return true
+	}
+	switch ref.Scheme() {
+	case "file", "self":
+		hT.issueStore.AddIssue(issues.Issue{
+			Level:     issues.LevelError,
+			Message:   "relative reference, emails need absolute https URLs",
+			Reference: ref,
+			Check:     "Email",
+		})
+		return true
+	}
+	return false
+}
+
+// Email specific checks on a node of interest: no scripts, no external
+// stylesheets and images sized up front.
+func (hT *HTMLTest) checkEmailNode(document *htmldoc.Document, node *html.Node) {
+	attrs := htmldoc.ExtractAttrs(node.Attr, []string{"src", "href", "rel"})
+	switch node.Data {
+	case "script":
+		hT.addNodeIssue("Email", issues.LevelError, document, node, attrs["src"], "script not allowed in email")
+	case "link":
+		if relContains(attrs["rel"], "stylesheet") {
+			hT.addNodeIssue("Email", issues.LevelError, document, node, attrs["href"],
+				"external stylesheet not allowed in email, inline styles instead")
+		}
+	case "img":
+		// Clients blocking images lay the email out without them
+		for _, attr := range []string{"width", "height"} {
+			if !htmldoc.AttrPresent(node.Attr, attr) {
+				hT.addNodeIssue("Email", issues.LevelError, document, node, attrs["src"],
+					fmt.Sprintf("%s attribute missing, email images need dimensions", attr))
+			}
+		}
+	}
+}
+
+// Email clients clip or truncate large messages, Gmail beyond 102KB.
+func (hT *HTMLTest) checkEmailSize(document *htmldoc.Document) {
+	if hT.opts.EmailMaxBytes <= 0 {
+		return
+	}
+	fi, err := os.Stat(document.FilePath)
+	if err != nil || fi.Size() <= int64(hT.opts.EmailMaxBytes) {
+		return
+	}
+	hT.issueStore.AddIssue(issues.Issue{
+		Level:    issues.LevelError,
+		Document: document,
+		Message:  fmt.Sprintf("email is %d bytes, clients clip beyond %d", fi.Size(), hT.opts.EmailMaxBytes),
+		Check:    "Email",
+	})
+}
@@ -0,0 +1,57 @@
+package htmltest
+
+import (
+	"testing"
+
+	"github.com/daviddengcn/go-assert"
+)
+
+// Options for testing an email, external links aren't fetched
+func tEmailOpts() map[string]interface{} {
+	return map[string]interface{}{
+		"Profile":       "email",
+		"CheckExternal": false,
+		"IgnoreURLs":    []interface{}{"^{{"},
+	}
+}
+
+func TestEmailProfileWebByDefault(t *testing.T) {
+	// relative links are fine on the web
+	hT := tTestFileOpts("fixtures/email/broken.html",
+		map[string]interface{}{"CheckExternal": false, "CheckInternal": false})
+	tExpectIssue(t, hT, "relative reference", 0)
+	tExpectIssue(t, hT, "not allowed in email", 0)
+}
+
+func TestEmailProfileValid(t *testing.T) {
+	// absolute https, mailto and ignored merge tags pass
+	hT := tTestFileOpts("fixtures/email/welcome.html", tEmailOpts())
+	tExpectIssueCount(t, hT, 0)
+}
+
+func TestEmailProfileBroken(t *testing.T) {
+	hT := tTestFileOpts("fixtures/email/broken.html", tEmailOpts())
+	tExpectIssue(t, hT, "relative reference, emails need absolute https URLs", 3)
+	tExpectIssue(t, hT, "protocol relative reference", 1)
+	tExpectIssue(t, hT, "is not an HTTPS target", 1)
+	tExpectIssue(t, hT, "script not allowed in email", 1)
+	tExpectIssue(t, hT, "external stylesheet not allowed in email", 1)
+	tExpectIssue(t, hT, "height attribute missing", 1)
+	tExpectIssue(t, hT, "width attribute missing", 0)
+	tExpectIssueCount(t, hT, 7)
+}
+
+func TestEmailProfileSize(t *testing.T) {
+	// large emails are clipped by clients
+	opts := tEmailOpts()
+	opts["EmailMaxBytes"] = 100
+	hT := tTestFileOpts("fixtures/email/welcome.html", opts)
+	tExpectIssue(t, hT, "clients clip beyond 100", 1)
+}
+
+func TestEmailProfileUnknown(t *testing.T) {
+	opts := defaultFileTestOpts("fixtures/email/welcome.html")
+	opts["Profile"] = "print"
+	_, err := Test(opts)
+	assert.NotEquals(t, "error", err, nil)
+}
@@ -22,6 +22,7 @@ func (hT *HTMLTest) checkGeneric(document *htmldoc.Document, node *html.Node, ke
 			Level:    issues.LevelError,
 			Document: document,
 			Message:  fmt.Sprintf("bad reference: %q", err),
+			Check:    issues.NodeCheckName(node.Data),
 		})
 		return
 	}
@@ -40,6 +41,10 @@ func (hT *HTMLTest) checkGeneric(document *htmldoc.Document, node *html.Node, ke
 }
 
 func (hT *HTMLTest) checkGenericRef(ref *htmldoc.Reference) {
+	if hT.checkEmailRelative(ref) {
+		return
+	}
+
 	// Route reference check
 	switch ref.Scheme() {
 	case "http":
@@ -62,7 +67,7 @@ func (hT *HTMLTest) enforceHTTPS(ref *htmldoc.Reference) {
 		issueLevel = issues.LevelWarning
 	}
 
-	if hT.opts.EnforceHTTPS {
+	if hT.opts.EnforceHTTPS || hT.opts.Profile == profileEmail {
 		hT.issueStore.AddIssue(issues.Issue{
 			Level:     issueLevel,
 			Message:   "is not an HTTPS target",
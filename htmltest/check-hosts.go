@@ -0,0 +1,211 @@
+package htmltest
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/wjdp/htmltest/htmldoc"
+	"github.com/wjdp/htmltest/issues"
+)
+
+// Given to hooks for URLs not requested as their host is down
+var errHostDown = errors.New("host down")
+
+// hostHealth : connection failures to a host during the run
+type hostHealth struct {
+	consecutive int             // Connection failures since the last response
+	down        bool            // Set once consecutive reaches HostFailureThreshold
+	urls        map[string]bool // Failed since the last response, and skipped once down
+	pending     []issues.Issue  // Failures held back until the host responds or is down
+}
+
+// HostOutage : a host marked down during the run and the URLs affected
+type HostOutage struct {
+	Host string
+	URLs []string
+}
+
+// Did err happen before the host could answer, looking it up, connecting or
+// during the TLS handshake? Requests which time out once connected, and
+// certificate errors, don't count, the host is up.
+func isConnectionFailure(err error) bool {
+	if uErr, ok := err.(*url.Error); ok {
+		err = uErr.Err
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	var recordErr tls.RecordHeaderError
+	if errors.As(err, &recordErr) {
+		return true
+	}
+	msg := err.Error()
+	if strings.Contains(msg, "x509") || strings.Contains(msg, "certificate") {
+		return false
+	}
+	return strings.Contains(msg, "TLS handshake timeout") || strings.HasPrefix(msg, "tls: ") ||
+		strings.HasPrefix(msg, "remote error: tls: ")
+}
+
+// The health of host, created on first use. Hold hostHealthMutex.
+func (hT *HTMLTest) hostHealthOf(host string) *hostHealth {
+	if hT.hostHealthMap == nil {
+		hT.hostHealthMap = make(map[string]*hostHealth)
+	}
+	host = strings.ToLower(host)
+	health, ok := hT.hostHealthMap[host]
+	if !ok {
+		health = &hostHealth{urls: make(map[string]bool)}
+		hT.hostHealthMap[host] = health
+	}
+	return health
+}
+
+// Is host marked down? If so urlStr is recorded against it and shouldn't be
+// requested.
+func (hT *HTMLTest) hostSkip(host string, urlStr string) bool {
+	if hT.opts.HostFailureThreshold <= 0 {
+		return false
+	}
+	hT.hostHealthMutex.Lock()
+	defer hT.hostHealthMutex.Unlock()
+	health := hT.hostHealthOf(host)
+	if health.down {
+		health.urls[urlStr] = true
+	}
+	return health.down
+}
+
+// Record a connection failure requesting urlStr, issue describing it. The
+// issue is held back until the host responds, when it's added, or is marked
+// down, when it's reported with the host.
+func (hT *HTMLTest) hostFailed(host string, urlStr string, issue issues.Issue) {
+	if hT.opts.HostFailureThreshold <= 0 {
+		hT.issueStore.AddIssue(issue)
+		return
+	}
+	hT.hostHealthMutex.Lock()
+	health := hT.hostHealthOf(host)
+	health.urls[urlStr] = true
+	if health.down {
+		// In flight when the host was marked down
+		hT.hostHealthMutex.Unlock()
+		hT.hostDownSkipped(issue.Reference, urlStr)
+		return
+	}
+	health.consecutive++
+	health.pending = append(health.pending, issue)
+	var folded []issues.Issue
+	if health.consecutive >= hT.opts.HostFailureThreshold {
+		health.down = true
+		folded, health.pending = health.pending, nil
+	}
+	hT.hostHealthMutex.Unlock()
+
+	for _, issue := range folded {
+		hT.hostDownSkipped(issue.Reference, hT.externalURL(issue.Reference.URLString()))
+	}
+}
+
+// Record a response from host, failures held back are added. Requests in
+// flight when a host was marked down may still get one, the host stays down.
+func (hT *HTMLTest) hostResponded(host string) {
+	if hT.opts.HostFailureThreshold <= 0 {
+		return
+	}
+	hT.hostHealthMutex.Lock()
+	health := hT.hostHealthOf(host)
+	var pending []issues.Issue
+	if !health.down {
+		pending = health.pending
+		health.consecutive = 0
+		health.urls = make(map[string]bool)
+		health.pending = nil
+	}
+	hT.hostHealthMutex.Unlock()
+
+	for _, issue := range pending {
+		hT.issueStore.AddIssue(issue)
+	}
+}
+
+// Note a URL not checked, or whose failure isn't reported on its own, as its
+// host is down. It still counts as failed for OnlyFailed.
+func (hT *HTMLTest) hostDownSkipped(ref *htmldoc.Reference, urlStr string) {
+	hT.issueStore.AddIssue(issues.Issue{
+		Level:     issues.LevelDebug,
+		Message:   "host down, reported once for the host",
+		Reference: ref,
+		Check:     "Hosts",
+	})
+	sitePath := ""
+	if ref.Document != nil {
+		sitePath = ref.Document.SitePath
+	}
+	hT.failures.add(sitePath, urlStr)
+}
+
+// HostsDown : Hosts marked down during the run, sorted, with the URLs which
+// failed or weren't checked because of it.
+func (hT *HTMLTest) HostsDown() []HostOutage {
+	hT.hostHealthMutex.Lock()
+	defer hT.hostHealthMutex.Unlock()
+	outages := make([]HostOutage, 0)
+	for host, health := range hT.hostHealthMap {
+		if !health.down {
+			continue
+		}
+		outage := HostOutage{Host: host, URLs: make([]string, 0, len(health.urls))}
+		for urlStr := range health.urls {
+			outage.URLs = append(outage.URLs, urlStr)
+		}
+		sort.Strings(outage.URLs)
+		outages = append(outages, outage)
+	}
+	sort.Slice(outages, func(i, j int) bool { return outages[i].Host < outages[j].Host })
+	return outages
+}
+
+// Site level check, run once all external checks are done. Failures held
+// back for hosts which never reached the threshold are added, then a single
+// issue for each host marked down, listing the URLs affected.
+func (hT *HTMLTest) checkHosts() {
+	hT.hostHealthMutex.Lock()
+	hosts := make([]string, 0, len(hT.hostHealthMap))
+	for host := range hT.hostHealthMap {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	pending := make([]issues.Issue, 0)
+	for _, host := range hosts {
+		pending = append(pending, hT.hostHealthMap[host].pending...)
+		hT.hostHealthMap[host].pending = nil
+	}
+	hT.hostHealthMutex.Unlock()
+	for _, issue := range pending {
+		hT.issueStore.AddIssue(issue)
+	}
+
+	issueLevel := issues.LevelError
+	if hT.opts.IgnoreExternalBrokenLinks {
+		issueLevel = issues.LevelWarning
+	}
+	for _, outage := range hT.HostsDown() {
+		hT.issueStore.AddIssue(issues.Issue{
+			Level: issueLevel,
+			Message: fmt.Sprintf("host %s down after %d connection failures, %d URLs affected: %s",
+				outage.Host, hT.opts.HostFailureThreshold, len(outage.URLs), strings.Join(outage.URLs, ", ")),
+			Check: "Hosts",
+		})
+	}
+}
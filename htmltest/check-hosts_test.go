@@ -0,0 +1,92 @@
+package htmltest
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/daviddengcn/go-assert"
+	"github.com/wjdp/htmltest/issues"
+)
+
+// A server URL which refuses connections.
+func tClosedServerURL() string {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	return server.URL
+}
+
+func TestHostDown(t *testing.T) {
+	// after three failures the host is down and reported once
+	hT := tTestExternalLinks(tClosedServerURL(), tPaths(8),
+		map[string]interface{}{"HostFailureThreshold": 3})
+	tExpectIssue(t, hT, "connection refused", 0)
+	tExpectIssue(t, hT, "down after 3 connection failures, 8 URLs affected", 1)
+	tExpectIssue(t, hT, "host down, reported once for the host", 8)
+	tExpectIssueCount(t, hT, 1)
+	outages := hT.HostsDown()
+	assert.Equals(t, "hosts down", len(outages), 1)
+	assert.Equals(t, "URLs affected", len(outages[0].URLs), 8)
+}
+
+func TestHostFewFailures(t *testing.T) {
+	// failures below the threshold are reported on their own at the end
+	hT := tTestExternalLinks(tClosedServerURL(), tPaths(2),
+		map[string]interface{}{"HostFailureThreshold": 3})
+	tExpectIssue(t, hT, "connection refused", 2)
+	tExpectIssue(t, hT, "down after", 0)
+	tExpectIssueCount(t, hT, 2)
+}
+
+func TestHostDownWarning(t *testing.T) {
+	hT := tTestExternalLinks(tClosedServerURL(), tPaths(4),
+		map[string]interface{}{"HostFailureThreshold": 2, "IgnoreExternalBrokenLinks": true})
+	tExpectIssueCount(t, hT, 0)
+	tExpectIssue(t, hT, "down after 2 connection failures, 4 URLs affected", 1)
+}
+
+func TestHostDownDisabled(t *testing.T) {
+	hT := tTestExternalLinks(tClosedServerURL(), tPaths(4),
+		map[string]interface{}{"HostFailureThreshold": 0})
+	tExpectIssue(t, hT, "connection refused", 4)
+	tExpectIssueCount(t, hT, 4)
+	assert.Equals(t, "hosts down", len(hT.HostsDown()), 0)
+}
+
+func TestHostResponseResets(t *testing.T) {
+	// failures are held back until the host responds, then added
+	hT := tTestFileOpts("fixtures/links/brokenLinkExternal.html",
+		map[string]interface{}{"NoRun": true, "HostFailureThreshold": 3})
+	failure := issues.Issue{Level: issues.LevelError, Message: "connection refused"}
+	hT.hostFailed("example.com", "http://example.com/1", failure)
+	hT.hostFailed("example.com", "http://example.com/2", failure)
+	tExpectIssueCount(t, hT, 0)
+	hT.hostResponded("example.com")
+	tExpectIssueCount(t, hT, 2)
+	hT.hostFailed("example.com", "http://example.com/3", failure)
+	assert.Equals(t, "skip", hT.hostSkip("example.com", "http://example.com/4"), false)
+	assert.Equals(t, "hosts down", len(hT.HostsDown()), 0)
+}
+
+func TestHostConnectionFailures(t *testing.T) {
+	// only lookup, dial and TLS errors count against a host
+	dial := &url.Error{Op: "Get", URL: "http://x", Err: &net.OpError{Op: "dial", Net: "tcp",
+		Err: errors.New("connect: connection refused")}}
+	assert.IsTrue(t, "dial", isConnectionFailure(dial))
+	lookup := &url.Error{Op: "Get", URL: "http://x", Err: &net.OpError{Op: "dial", Net: "tcp",
+		Err: &net.DNSError{Err: "no such host", Name: "x"}}}
+	assert.IsTrue(t, "lookup", isConnectionFailure(lookup))
+	handshake := &url.Error{Op: "Get", URL: "https://x", Err: errors.New("net/http: TLS handshake timeout")}
+	assert.IsTrue(t, "handshake", isConnectionFailure(handshake))
+	timeout := &url.Error{Op: "Get", URL: "http://x",
+		Err: errors.New("net/http: request canceled (Client.Timeout exceeded while awaiting headers)")}
+	assert.IsFalse(t, "request timeout", isConnectionFailure(timeout))
+	cert := &url.Error{Op: "Get", URL: "https://x", Err: errors.New("x509: certificate has expired")}
+	assert.IsFalse(t, "certificate", isConnectionFailure(cert))
+	verify := &url.Error{Op: "Get", URL: "https://x",
+		Err: errors.New("tls: failed to verify certificate: x509: certificate signed by unknown authority")}
+	assert.IsFalse(t, "verify", isConnectionFailure(verify))
+}
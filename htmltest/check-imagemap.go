@@ -0,0 +1,200 @@
+package htmltest
+
+import (
+	"fmt"
+	"image"
+	// Register decoders for image.DecodeConfig
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/wjdp/htmltest/htmldoc"
+	"github.com/wjdp/htmltest/issues"
+	"golang.org/x/net/html"
+)
+
+// Number of coords each area shape takes, poly is checked separately
+var areaShapeCoords = map[string]int{
+	"rect":    4,
+	"circle":  3,
+	"poly":    -1,
+	"default": 0,
+}
+
+// Validates the <map> an <img usemap> points to and each of its areas. A map
+// shared by several images is validated once, its areas are checked against
+// the bounds of each image.
+func (hT *HTMLTest) checkImageMap(document *htmldoc.Document, node *html.Node, ref *htmldoc.Reference, name string) {
+	mapNode, ok := document.GetMap(name)
+	if !ok {
+		hT.issueStore.AddIssue(issues.Issue{
+			Level:     issues.LevelError,
+			Message:   "usemap does not match a <map name>",
+			Reference: ref,
+			Check:     "Image maps",
+		})
+		return
+	}
+
+	if document.State.ImageMapsChecked == nil {
+		document.State.ImageMapsChecked = make(map[string]bool)
+	}
+	validate := !document.State.ImageMapsChecked[name]
+	document.State.ImageMapsChecked[name] = true
+
+	width, height := hT.imageDimensions(node, ref)
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && c.Data == "area" {
+				hT.checkArea(document, c, validate, width, height)
+			}
+			walk(c)
+		}
+	}
+	walk(mapNode)
+}
+
+// Checks the shape, coords and alt text of a single <area> when validate is
+// set. Coords are checked against the image bounds when width and height are
+// non-zero.
+func (hT *HTMLTest) checkArea(document *htmldoc.Document, node *html.Node, validate bool, width int, height int) {
+	attrs := htmldoc.ExtractAttrs(node.Attr, []string{"href", "alt", "shape", "coords"})
+
+	ref, err := htmldoc.NewReference(document, node, attrs["href"])
+	if err != nil {
+		if validate {
+			hT.issueStore.AddIssue(issues.Issue{
+				Level:    issues.LevelError,
+				Document: document,
+				Message:  fmt.Sprintf("bad reference: %q", err),
+				Check:    "Image maps",
+			})
+		}
+		return
+	}
+
+	if validate && htmldoc.AttrPresent(node.Attr, "href") && !hT.opts.IgnoreAltMissing &&
+		strings.TrimSpace(attrs["alt"]) == "" {
+		hT.issueStore.AddIssue(issues.Issue{
+			Level:     issues.LevelError,
+			Message:   "area with href missing alt text",
+			Reference: ref,
+			Check:     "Image maps",
+		})
+	}
+
+	shape, coords, message := areaGeometry(attrs["shape"], attrs["coords"])
+	if message != "" {
+		if validate {
+			hT.issueStore.AddIssue(issues.Issue{
+				Level:     issues.LevelError,
+				Message:   message,
+				Reference: ref,
+				Check:     "Image maps",
+			})
+		}
+		return
+	}
+
+	if shape == "default" || width == 0 || height == 0 {
+		return
+	}
+	// Bounding box of the area, circles extend by their radius
+	var xs, ys []float64
+	if shape == "circle" {
+		xs = []float64{coords[0] - coords[2], coords[0] + coords[2]}
+		ys = []float64{coords[1] - coords[2], coords[1] + coords[2]}
+	} else {
+		for i := 0; i < len(coords); i += 2 {
+			xs = append(xs, coords[i])
+			ys = append(ys, coords[i+1])
+		}
+	}
+	for i := range xs {
+		if xs[i] < 0 || ys[i] < 0 || xs[i] > float64(width) || ys[i] > float64(height) {
+			hT.issueStore.AddIssue(issues.Issue{
+				Level:     issues.LevelError,
+				Message:   fmt.Sprintf("area coords outside image bounds (%dx%d)", width, height),
+				Reference: ref,
+				Check:     "Image maps",
+			})
+			return
+		}
+	}
+}
+
+// Parse an area's shape and coords, a missing shape being a rect. Returns a
+// message describing the first problem found, empty if there are none.
+func areaGeometry(shapeAttr string, coordsAttr string) (string, []float64, string) {
+	shape := strings.ToLower(strings.TrimSpace(shapeAttr))
+	if shape == "" {
+		shape = "rect"
+	}
+	expected, ok := areaShapeCoords[shape]
+	if !ok {
+		return shape, nil, fmt.Sprintf("area shape invalid: %q", shapeAttr)
+	}
+	if shape == "default" {
+		return shape, nil, ""
+	}
+
+	coords, ok := parseAreaCoords(coordsAttr)
+	if !ok {
+		return shape, nil, fmt.Sprintf("area coords not numeric: %q", coordsAttr)
+	}
+	if (expected > 0 && len(coords) != expected) || (expected < 0 && (len(coords) < 6 || len(coords)%2 != 0)) {
+		want := strconv.Itoa(expected)
+		if expected < 0 {
+			want = "an even number, at least 6,"
+		}
+		return shape, nil, fmt.Sprintf("area %s takes %s coords, found %d", shape, want, len(coords))
+	}
+	if shape == "circle" && coords[2] <= 0 {
+		return shape, nil, "area circle radius must be positive"
+	}
+	return shape, coords, ""
+}
+
+// Intrinsic dimensions of an image, from a local image file or failing that
+// its width/height attributes. Zero when unknown.
+func (hT *HTMLTest) imageDimensions(node *html.Node, ref *htmldoc.Reference) (int, int) {
+	if ref.Scheme() == "file" {
+		if f, err := os.Open(path.Join(hT.opts.DirectoryPath, ref.RefSitePath())); err == nil {
+			config, _, err := image.DecodeConfig(f)
+			f.Close()
+			if err == nil {
+				return config.Width, config.Height
+			}
+		}
+	}
+
+	attrs := htmldoc.ExtractAttrs(node.Attr, []string{"width", "height"})
+	width, errW := strconv.Atoi(strings.TrimSpace(attrs["width"]))
+	height, errH := strconv.Atoi(strings.TrimSpace(attrs["height"]))
+	if errW != nil || errH != nil {
+		return 0, 0
+	}
+	return width, height
+}
+
+// Parse a comma separated list of numbers. Whitespace is tolerated.
+func parseAreaCoords(coords string) ([]float64, bool) {
+	values := make([]float64, 0)
+	if strings.TrimSpace(coords) == "" {
+		return values, true
+	}
+	for _, s := range strings.Split(coords, ",") {
+		v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return nil, false
+		}
+		values = append(values, v)
+	}
+	return values, true
+}
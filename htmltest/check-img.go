@@ -19,6 +19,7 @@ func (hT *HTMLTest) checkImg(document *htmldoc.Document, node *html.Node) {
 			Level:    issues.LevelError,
 			Document: document,
 			Message:  fmt.Sprintf("bad reference: %q", err),
+			Check:    issues.NodeCheckName(node.Data),
 		})
 		return
 	}
@@ -76,6 +77,7 @@ func (hT *HTMLTest) checkImg(document *htmldoc.Document, node *html.Node) {
 				Level:    issues.LevelError,
 				Document: document,
 				Message:  fmt.Sprintf("bad reference: %q", err),
+				Check:    issues.NodeCheckName(node.Data),
 			})
 			return
 		}
@@ -92,8 +94,16 @@ func (hT *HTMLTest) checkImg(document *htmldoc.Document, node *html.Node) {
 				Message:   "usemap empty",
 				Reference: ref,
 			})
-		} else {
-			hT.checkInternalHash(usemapRef)
+		} else if hT.opts.CheckImageMaps {
+			hT.checkImageMap(document, node, ref, usemapRef.URL.Fragment)
+		} else if _, ok := document.GetMap(usemapRef.URL.Fragment); !ok && hT.opts.CheckInternalHash &&
+			!document.IsHashValid(usemapRef.URL.Fragment) {
+			// usemap names a <map>, ids are tolerated unless CheckImageMaps is on
+			hT.issueStore.AddIssue(issues.Issue{
+				Level:     issues.LevelError,
+				Message:   "hash does not exist",
+				Reference: usemapRef,
+			})
 		}
 
 		parent := node.Parent
@@ -112,6 +122,10 @@ func (hT *HTMLTest) checkImg(document *htmldoc.Document, node *html.Node) {
 		}
 	}
 
+	if hT.checkEmailRelative(ref) {
+		return
+	}
+
 	// Route reference check
 	switch ref.Scheme() {
 	case "http":
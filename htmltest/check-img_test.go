@@ -255,3 +255,56 @@ func TestImageMultipleProblems(t *testing.T) {
 	tExpectIssue(t, hT, "alt attribute missing", 1)
 	tExpectIssue(t, hT, "src attribute missing", 1)
 }
+
+func TestImageMapValid(t *testing.T) {
+	// passes for a map with valid areas inside the image
+	hT := tTestFileOpts("fixtures/images/imagemapValid.html",
+		map[string]interface{}{"CheckImageMaps": true})
+	tExpectIssueCount(t, hT, 0)
+}
+
+func TestImageMapIDOnly(t *testing.T) {
+	// fails for usemap matching an id rather than a map name
+	hT := tTestFileOpts("fixtures/images/imagemapIdOnly.html",
+		map[string]interface{}{"CheckImageMaps": true})
+	tExpectIssueCount(t, hT, 1)
+	tExpectIssue(t, hT, "usemap does not match a <map name>", 1)
+}
+
+func TestImageMapIDOnlyDefault(t *testing.T) {
+	// passes for usemap matching an id when map checking is off
+	hT := tTestFile("fixtures/images/imagemapIdOnly.html")
+	tExpectIssueCount(t, hT, 0)
+}
+
+func TestImageMapAreasInvalid(t *testing.T) {
+	// fails for areas with bad shapes, coords or alt
+	hT := tTestFileOpts("fixtures/images/imagemapAreasInvalid.html",
+		map[string]interface{}{"CheckImageMaps": true})
+	tExpectIssueCount(t, hT, 6)
+	tExpectIssue(t, hT, "area with href missing alt text", 1)
+	tExpectIssue(t, hT, "area shape invalid: \"triangle\"", 1)
+	tExpectIssue(t, hT, "area rect takes 4 coords, found 3", 1)
+	tExpectIssue(t, hT, "area poly takes an even number, at least 6, coords, found 5", 1)
+	tExpectIssue(t, hT, "area coords not numeric", 1)
+	tExpectIssue(t, hT, "area circle radius must be positive", 1)
+}
+
+func TestImageMapAreasOutOfBounds(t *testing.T) {
+	// fails for areas outside the intrinsic or, for images that can't be read,
+	// attribute given dimensions
+	hT := tTestFileOpts("fixtures/images/imagemapOutOfBounds.html",
+		map[string]interface{}{"CheckImageMaps": true})
+	tExpectIssueCount(t, hT, 3)
+	tExpectIssue(t, hT, "area coords outside image bounds (256x256)", 2)
+	tExpectIssue(t, hT, "area coords outside image bounds (50x50)", 1)
+}
+
+func TestImageMapShared(t *testing.T) {
+	// validates a shared map once, checking bounds for each image
+	hT := tTestFileOpts("fixtures/images/imagemapShared.html",
+		map[string]interface{}{"CheckImageMaps": true})
+	tExpectIssueCount(t, hT, 3)
+	tExpectIssue(t, hT, "area rect takes 4 coords, found 3", 1)
+	tExpectIssue(t, hT, "area coords outside image bounds (50x50)", 2)
+}
@@ -0,0 +1,256 @@
+package htmltest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/wjdp/htmltest/htmldoc"
+	"github.com/wjdp/htmltest/issues"
+	"golang.org/x/net/html"
+)
+
+// Landmark roles of elements, header and footer are handled by landmarkRole
+var landmarkElements = map[string]string{
+	"main":  "main",
+	"nav":   "navigation",
+	"aside": "complementary",
+}
+
+// Explicit roles which make an element a landmark
+var landmarkRoles = map[string]bool{
+	"banner":        true,
+	"complementary": true,
+	"contentinfo":   true,
+	"form":          true,
+	"main":          true,
+	"navigation":    true,
+	"region":        true,
+	"search":        true,
+}
+
+// Roles header and footer take when they aren't inside sectioning content
+var landmarkScoped = map[string]string{
+	"header": "banner",
+	"footer": "contentinfo",
+}
+
+// Elements and roles which scope header and footer, so they aren't banner or
+// contentinfo
+var landmarkSectioning = map[string]bool{
+	"article":       true,
+	"aside":         true,
+	"main":          true,
+	"nav":           true,
+	"section":       true,
+	"complementary": true,
+	"navigation":    true,
+	"region":        true,
+}
+
+// The landmark role of n, explicit or implicit, empty if it isn't one.
+func landmarkRole(n *html.Node) string {
+	if role := strings.ToLower(strings.TrimSpace(htmldoc.GetAttr(n.Attr, "role"))); role != "" {
+		// The first role is the one used, other roles such as dialog or list
+		// override the element's own
+		if role = strings.Fields(role)[0]; landmarkRoles[role] {
+			return role
+		}
+		return ""
+	}
+	if role, ok := landmarkElements[n.Data]; ok {
+		return role
+	}
+	if role, ok := landmarkScoped[n.Data]; ok {
+		for p := n.Parent; p != nil; p = p.Parent {
+			if p.Type == html.ElementNode &&
+				(landmarkSectioning[p.Data] || landmarkSectioning[htmldoc.GetAttr(p.Attr, "role")]) {
+				return ""
+			}
+		}
+		return role
+	}
+	return ""
+}
+
+// Label distinguishing a landmark from others of its role, empty if none.
+func landmarkLabel(document *htmldoc.Document, n *html.Node) string {
+	if label := strings.TrimSpace(htmldoc.GetAttr(n.Attr, "aria-label")); label != "" {
+		return label
+	}
+	texts := make([]string, 0)
+	for _, id := range strings.Fields(htmldoc.GetAttr(n.Attr, "aria-labelledby")) {
+		if target, ok := document.GetHashTarget(id); ok {
+			texts = append(texts, strings.Join(strings.Fields(innerText(target)), " "))
+		}
+	}
+	return strings.Join(texts, " ")
+}
+
+// Text of n and all its descendants.
+func innerText(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var sb strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		sb.WriteString(innerText(c))
+	}
+	return sb.String()
+}
+
+// Is n, or an ancestor, hidden from everyone?
+func isHiddenNode(n *html.Node) bool {
+	for ; n != nil; n = n.Parent {
+		if n.Type == html.ElementNode && (htmldoc.AttrPresent(n.Attr, "hidden") || htmldoc.AttrPresent(n.Attr, "inert")) {
+			return true
+		}
+	}
+	return false
+}
+
+// Landmark structure: one main, navigation and complementary landmarks
+// labelled when there's more than one, banner and contentinfo at the top
+// level.
+func (hT *HTMLTest) checkLandmarks(document *htmldoc.Document) {
+	addIssue := func(message string) {
+		hT.issueStore.AddIssue(issues.Issue{
+			Level:    issues.LevelError,
+			Document: document,
+			Message:  message,
+			Check:    "Landmarks",
+		})
+	}
+
+	mains := 0
+	byRole := make(map[string][]*html.Node)
+	document.Walk(func(n *html.Node) {
+		if n.Type != html.ElementNode || isHiddenNode(n) {
+			return
+		}
+		role := landmarkRole(n)
+		switch role {
+		case "main":
+			mains++
+		case "navigation", "complementary":
+			byRole[role] = append(byRole[role], n)
+		case "banner", "contentinfo":
+			for p := n.Parent; p != nil; p = p.Parent {
+				if p.Type != html.ElementNode {
+					continue
+				}
+				if parentRole := landmarkRole(p); parentRole != "" {
+					addIssue(fmt.Sprintf("%s landmark nested in %s landmark", role, parentRole))
+					break
+				}
+			}
+		}
+	})
+
+	if mains == 0 {
+		addIssue("no main landmark, add <main>")
+	} else if mains > 1 {
+		addIssue(fmt.Sprintf("%d main landmarks, expected one", mains))
+	}
+
+	for _, role := range []string{"navigation", "complementary"} {
+		nodes := byRole[role]
+		if len(nodes) < 2 {
+			continue
+		}
+		unlabelled := 0
+		labels := make(map[string]int)
+		for _, n := range nodes {
+			if label := landmarkLabel(document, n); label == "" {
+				unlabelled++
+			} else {
+				labels[label]++
+			}
+		}
+		if unlabelled > 0 {
+			addIssue(fmt.Sprintf("%d of %d %s landmarks unlabelled, add aria-label to tell them apart",
+				unlabelled, len(nodes), role))
+		}
+		for label, count := range labels {
+			if count > 1 {
+				addIssue(fmt.Sprintf("%d %s landmarks labelled %q", count, role, label))
+			}
+		}
+	}
+}
+
+// Is n focusable, in the tab order unless allowNegative is set? Natively
+// focusable elements and those with a tabindex count, unless disabled or
+// hidden.
+func isFocusable(n *html.Node, allowNegative bool) bool {
+	if n.Type != html.ElementNode || isHiddenNode(n) {
+		return false
+	}
+	if htmldoc.AttrPresent(n.Attr, "tabindex") {
+		tabIndex, err := strconv.Atoi(strings.TrimSpace(htmldoc.GetAttr(n.Attr, "tabindex")))
+		if err == nil {
+			return allowNegative || tabIndex >= 0
+		}
+	}
+	switch n.Data {
+	case "a", "area":
+		return htmldoc.AttrPresent(n.Attr, "href")
+	case "input":
+		if strings.EqualFold(htmldoc.GetAttr(n.Attr, "type"), "hidden") {
+			return false
+		}
+		return !htmldoc.AttrPresent(n.Attr, "disabled")
+	case "button", "select", "textarea":
+		return !htmldoc.AttrPresent(n.Attr, "disabled")
+	case "iframe", "summary":
+		return true
+	}
+	return strings.EqualFold(htmldoc.GetAttr(n.Attr, "contenteditable"), "true")
+}
+
+// The first focusable element must be a skip link, <a href="#...">,
+// resolving to a focusable target.
+func (hT *HTMLTest) checkSkipLink(document *htmldoc.Document) {
+	var first *html.Node
+	document.Walk(func(n *html.Node) {
+		if first == nil && isFocusable(n, false) {
+			first = n
+		}
+	})
+
+	href := ""
+	if first != nil && first.Data == "a" {
+		href = strings.TrimSpace(htmldoc.GetAttr(first.Attr, "href"))
+	}
+	if !strings.HasPrefix(href, "#") || len(href) < 2 {
+		hT.issueStore.AddIssue(issues.Issue{
+			Level:    issues.LevelError,
+			Document: document,
+			Message:  "no skip link, the first focusable element should be <a href=\"#content\">",
+			Check:    "Skip links",
+		})
+		return
+	}
+
+	ref, err := htmldoc.NewReference(document, first, href)
+	if err != nil {
+		// Reported by checkLink
+		return
+	}
+	target, ok := document.GetHashTarget(ref.URL.Fragment)
+	if !ok {
+		hT.issueStore.AddIssue(issues.Issue{
+			Level:     issues.LevelError,
+			Message:   "skip link target does not exist",
+			Reference: ref,
+			Check:     "Skip links",
+		})
+	} else if !isFocusable(target, true) {
+		hT.issueStore.AddIssue(issues.Issue{
+			Level:     issues.LevelError,
+			Message:   "skip link target is not focusable, add tabindex=\"-1\"",
+			Reference: ref,
+			Check:     "Skip links",
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package htmltest
+
+import (
+	"testing"
+)
+
+func TestLandmarksDisabled(t *testing.T) {
+	hT := tTestFile("fixtures/landmarks/bad.html")
+	tExpectIssueCount(t, hT, 0)
+}
+
+func TestLandmarksGood(t *testing.T) {
+	hT := tTestFileOpts("fixtures/landmarks/good.html",
+		map[string]interface{}{"CheckLandmarks": true, "CheckSkipLinks": true})
+	tExpectIssueCount(t, hT, 0)
+}
+
+func TestLandmarksMain(t *testing.T) {
+	hT := tTestFileOpts("fixtures/landmarks/bad.html", map[string]interface{}{"CheckLandmarks": true})
+	tExpectIssue(t, hT, "2 main landmarks, expected one", 1)
+	hT2 := tTestFileOpts("fixtures/landmarks/no-main.html", map[string]interface{}{"CheckLandmarks": true})
+	tExpectIssue(t, hT2, "no main landmark, add <main>", 1)
+}
+
+func TestLandmarksUnlabelled(t *testing.T) {
+	hT := tTestFileOpts("fixtures/landmarks/bad.html", map[string]interface{}{"CheckLandmarks": true})
+	tExpectIssue(t, hT, "2 of 3 navigation landmarks unlabelled, add aria-label to tell them apart", 1)
+	tExpectIssue(t, hT, "2 complementary landmarks labelled \"Related\"", 1)
+}
+
+func TestLandmarksNested(t *testing.T) {
+	hT := tTestFileOpts("fixtures/landmarks/bad.html", map[string]interface{}{"CheckLandmarks": true})
+	tExpectIssue(t, hT, "banner landmark nested in main landmark", 1)
+	tExpectIssue(t, hT, "contentinfo landmark nested in navigation landmark", 1)
+	tExpectIssueCount(t, hT, 5)
+}
+
+func TestSkipLinkMissing(t *testing.T) {
+	hT := tTestFileOpts("fixtures/landmarks/no-main.html", map[string]interface{}{"CheckSkipLinks": true})
+	tExpectIssue(t, hT, "no skip link", 1)
+	tExpectIssueCount(t, hT, 1)
+}
+
+func TestSkipLinkTargetMissing(t *testing.T) {
+	hT := tTestFileOpts("fixtures/landmarks/missing-target.html",
+		map[string]interface{}{"CheckSkipLinks": true, "CheckInternalHash": false})
+	tExpectIssue(t, hT, "skip link target does not exist", 1)
+	tExpectIssueCount(t, hT, 1)
+}
+
+func TestSkipLinkTargetNotFocusable(t *testing.T) {
+	hT := tTestFileOpts("fixtures/landmarks/bad.html", map[string]interface{}{"CheckSkipLinks": true})
+	tExpectIssue(t, hT, "skip link target is not focusable", 1)
+	tExpectIssueCount(t, hT, 1)
+}
@@ -5,14 +5,13 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
-	"os"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/badoux/checkmail"
 	"github.com/wjdp/htmltest/htmldoc"
 	"github.com/wjdp/htmltest/issues"
-	"github.com/wjdp/htmltest/output"
 	"golang.org/x/net/html"
 )
 
@@ -40,10 +39,20 @@ func (hT *HTMLTest) checkLink(document *htmldoc.Document, node *html.Node) {
 			Level:    issues.LevelError,
 			Document: document,
 			Message:  fmt.Sprintf("bad reference: %q", err),
+			Check:    issues.NodeCheckName(node.Data),
 		})
 		return
 	}
 
+	// <a name> is only kept by the HTML spec for compatibility
+	if hT.opts.WarnLegacyAnchors && node.Data == "a" && htmldoc.AttrPresent(node.Attr, "name") {
+		hT.issueStore.AddIssue(issues.Issue{
+			Level:     issues.LevelWarning,
+			Message:   fmt.Sprintf("legacy anchor <a name=%q>, use an id instead", htmldoc.GetAttr(node.Attr, "name")),
+			Reference: ref,
+		})
+	}
+
 	// Check for missing href, fail for link nodes
 	if !htmldoc.AttrPresent(node.Attr, "href") {
 		switch node.Data {
@@ -88,6 +97,10 @@ func (hT *HTMLTest) checkLink(document *htmldoc.Document, node *html.Node) {
 		return
 	}
 
+	if hT.checkEmailRelative(ref) {
+		return
+	}
+
 	// Route reference check
 	switch ref.Scheme() {
 	case "http":
@@ -97,6 +110,12 @@ func (hT *HTMLTest) checkLink(document *htmldoc.Document, node *html.Node) {
 		hT.checkExternal(ref)
 	case "file":
 		hT.checkInternal(ref)
+		if node.Data == "a" {
+			hT.checkLanguageLink(ref, node)
+		}
+		if node.Data == "link" && relContains(attrs["rel"], "stylesheet") {
+			hT.checkSourceMap(ref)
+		}
 	case "self":
 		hT.checkInternalHash(ref)
 	case "mailto":
@@ -133,16 +152,31 @@ func (hT *HTMLTest) checkExternal(ref *htmldoc.Reference) {
 		return
 	}
 
-	if hT.opts.StripQueryString && !InList(hT.opts.StripQueryExcludes, urlStr) {
-		urlStr = htmldoc.URLStripQueryString(urlStr)
+	urlStr = hT.externalURL(urlStr)
+	if hT.onlyFailedSkipsURL(ref, urlStr) {
+		hT.issueStore.AddIssue(issues.Issue{
+			Level:     issues.LevelDebug,
+			Message:   "skipping external check, passed last run",
+			Reference: ref,
+		})
+		return
 	}
 	var statusCode int
+	var botBlocked bool
+
+	// Tell hooks about the outcome however we leave
+	event := ExternalEvent{Reference: ref, URL: urlStr, Started: time.Now()}
+	defer func() {
+		event.StatusCode = statusCode
+		hT.hooks.externalFinished(event)
+	}()
 
 	cR, isCached := hT.refCache.Get(urlStr)
 
 	if isCached && statusCodeValid(cR.StatusCode) {
 		// If we have a valid result in cache, use that
 		statusCode = cR.StatusCode
+		event.CacheHit = true
 		hT.issueStore.AddIssue(issues.Issue{
 			Level:     issues.LevelDebug,
 			Message:   "from cache",
@@ -155,37 +189,39 @@ func (hT *HTMLTest) checkExternal(ref *htmldoc.Reference) {
 			Reference: ref,
 		})
 
-		// Build the request
-		req, err := http.NewRequest("GET", urlStr, nil)
-		// Only error NewRequest raises is if the url isn't valid, we have already checked it by this point so OK just
-		// to panic if err != nil.
-		output.CheckErrorPanic(err)
-
-		// Set UA header
-		req.Header.Set("User-Agent", "htmltest/"+hT.opts.Version)
-
-		// Set headers from HTTPHeaders option
-		for key, value := range hT.opts.HTTPHeaders {
-			// Due to the way we're loading in config these keys and values are interface{}. In normal cases they are
-			// strings, but could very easily be ints (side note: this isn't great, we'll fix this later, #73)
-			req.Header.Set(fmt.Sprintf("%v", key), fmt.Sprintf("%v", value))
+		if hT.hostSkip(ref.URL.Host, urlStr) {
+			event.Err = errHostDown
+			hT.hostDownSkipped(ref, urlStr)
+			return
 		}
 
-		hT.httpChannel <- true // Add to http concurrency limiter
-
 		hT.issueStore.AddIssue(issues.Issue{
 			Level:     issues.LevelInfo,
 			Message:   "hitting",
 			Reference: ref,
 		})
 
-		resp, err := hT.httpClient.Do(req)
-
-		<-hT.httpChannel // Bump off http concurrency limiter
+		hT.hooks.externalStarted(ref, urlStr)
+		// Use the header profile known to work for this host, if any
+		profile := hT.refCache.GetHostProfile(ref.URL.Host)
+		resp, err := hT.doExternal(urlStr, profile)
+		if err == nil && hT.isBotBlockStatus(resp.StatusCode) {
+			botBlocked = true
+			if profile != profileBrowser {
+				resp, botBlocked = hT.retryAsBrowser(ref, urlStr, resp)
+			}
+		}
 
 		if err != nil {
+			event.Err = err
+			// Connection failures are held back until the host's health is known
+			addIssue := hT.issueStore.AddIssue
+			if isConnectionFailure(err) {
+				addIssue = func(issue issues.Issue) { hT.hostFailed(ref.URL.Host, urlStr, issue) }
+			}
+
 			if strings.Contains(err.Error(), "Client.Timeout") {
-				hT.issueStore.AddIssue(issues.Issue{
+				addIssue(issues.Issue{
 					Level:     issueLevel,
 					Message:   "request exceeded our ExternalTimeout",
 					Reference: ref,
@@ -193,10 +229,19 @@ func (hT *HTMLTest) checkExternal(ref *htmldoc.Reference) {
 				return
 			}
 
+			if message := transportTimeoutMessage(err); message != "" {
+				addIssue(issues.Issue{
+					Level:     issueLevel,
+					Message:   message,
+					Reference: ref,
+				})
+				return
+			}
+
 			if certErr, ok := err.(*url.Error).Err.(x509.UnknownAuthorityError); ok {
 				err = validateCertChain(certErr.Cert)
 				if err == nil {
-					hT.issueStore.AddIssue(issues.Issue{
+					addIssue(issues.Issue{
 						Level:     issues.LevelWarning,
 						Reference: ref,
 						Message:   "incomplete certificate chain",
@@ -211,28 +256,42 @@ func (hT *HTMLTest) checkExternal(ref *htmldoc.Reference) {
 				prefix := "Get " + urlStr + ": dial tcp: lookup "
 				cleanedMessage := strings.TrimPrefix(err.Error(), prefix)
 				// Add error
-				hT.issueStore.AddIssue(issues.Issue{
-					Level:     issueLevel,
-					Message:   cleanedMessage,
-					Reference: ref,
+				addIssue(issues.Issue{
+					Level:      issueLevel,
+					Message:    cleanedMessage,
+					Reference:  ref,
+					Suggestion: hT.deadLinkSnapshot(urlStr, 0, err),
 				})
 				return
 			}
 
 			// Unhandled client error, return generic error
-			hT.issueStore.AddIssue(issues.Issue{
-				Level:     issueLevel,
-				Message:   err.Error(),
-				Reference: ref,
+			addIssue(issues.Issue{
+				Level:      issueLevel,
+				Message:    err.Error(),
+				Reference:  ref,
+				Suggestion: hT.deadLinkSnapshot(urlStr, 0, err),
 			})
 
 			return
 		}
+		hT.hostResponded(ref.URL.Host)
+		// Only the status is needed, let the connection be reused
+		defer hT.closeBody(resp)
 		// Save cached result
 		hT.refCache.Save(urlStr, resp.StatusCode)
 		statusCode = resp.StatusCode
 	}
 
+	if botBlocked {
+		hT.issueStore.AddIssue(issues.Issue{
+			Level:     hT.opts.BotBlockedLevel,
+			Message:   fmt.Sprintf("blocked for bots, status %d", statusCode),
+			Reference: ref,
+		})
+		return
+	}
+
 	switch statusCode {
 	case http.StatusOK:
 		hT.issueStore.AddIssue(issues.Issue{
@@ -257,9 +316,10 @@ func (hT *HTMLTest) checkExternal(ref *htmldoc.Reference) {
 		} else {
 			// Failed VCRed requests end up here with a status code of zero
 			hT.issueStore.AddIssue(issues.Issue{
-				Level:     issueLevel,
-				Message:   fmt.Sprintf("%s %d", "Non-OK status:", statusCode),
-				Reference: ref,
+				Level:      issueLevel,
+				Message:    fmt.Sprintf("%s %d", "Non-OK status:", statusCode),
+				Reference:  ref,
+				Suggestion: hT.deadLinkSnapshot(urlStr, statusCode, nil),
 			})
 		}
 	}
@@ -282,6 +342,7 @@ func (hT *HTMLTest) checkInternal(ref *htmldoc.Reference) {
 
 	// Does this internal url match a internal url ignore rule?
 	if hT.opts.isInternalURLIgnored(urlStr) {
+		hT.markReferenced(ref.RefSitePath())
 		return
 	}
 
@@ -289,6 +350,7 @@ func (hT *HTMLTest) checkInternal(ref *htmldoc.Reference) {
 	refDoc, refExists := hT.documentStore.ResolveRef(ref)
 
 	if refExists {
+		hT.markReferenced(refDoc.SitePath)
 		// If the resolved ref is an index.html and the path doesn't end in a
 		// trailing slash (and isn't linking directly to the index), complain.
 		if !hT.opts.IgnoreDirectoryMissingTrailingSlash && path.Base(refDoc.SitePath) == hT.opts.DirectoryIndex &&
@@ -301,12 +363,17 @@ func (hT *HTMLTest) checkInternal(ref *htmldoc.Reference) {
 			refExists = false
 		}
 	} else {
-		// If that fails attempt to lookup with filesystem, resolve a path and check
-		refOsPath := path.Join(hT.opts.DirectoryPath, ref.RefSitePath())
-		refExists = hT.checkFile(ref, refOsPath)
+		// If that fails attempt to lookup in the asset index
+		refExists = hT.checkFile(ref, ref.RefSitePath())
 	}
 
-	if refExists && len(ref.URL.Fragment) > 0 {
+	if refExists && hT.isPDF(ref.RefSitePath()) {
+		// Read now so checkPDFs covers it
+		hT.pdfInspect(ref.RefSitePath())
+		if len(ref.URL.Fragment) > 0 {
+			hT.checkPDFFragment(ref, ref.RefSitePath())
+		}
+	} else if refExists && len(ref.URL.Fragment) > 0 {
 		// Is also a hash link
 		hT.checkInternalHash(ref)
 	}
@@ -333,8 +400,15 @@ func (hT *HTMLTest) checkInternalHash(ref *htmldoc.Reference) {
 
 	if len(ref.URL.Path) > 0 {
 		// internal
-		refDoc, _ := hT.documentStore.ResolveRef(ref)
-		if !refDoc.IsHashValid(ref.URL.Fragment) {
+		refDoc, isDoc := hT.documentStore.ResolveRef(ref)
+		if !isDoc {
+			// An asset such as an image, we can't see inside it
+			hT.issueStore.AddIssue(issues.Issue{
+				Level:     issues.LevelDebug,
+				Message:   "skipping hash check, target is not a document",
+				Reference: ref,
+			})
+		} else if !refDoc.IsHashValid(ref.URL.Fragment) {
 			hT.issueStore.AddIssue(issues.Issue{
 				Level:     issues.LevelError,
 				Message:   "hash does not exist",
@@ -353,21 +427,26 @@ func (hT *HTMLTest) checkInternalHash(ref *htmldoc.Reference) {
 	}
 }
 
-func (hT *HTMLTest) checkFile(ref *htmldoc.Reference, absPath string) bool {
-	f, err := os.Stat(absPath)
-	if os.IsNotExist(err) {
+// Check a non-document internal reference exists, using the asset index built
+// during discovery rather than the filesystem.
+func (hT *HTMLTest) checkFile(ref *htmldoc.Reference, sitePath string) bool {
+	isDir, exists := hT.documentStore.ResolveAsset(sitePath)
+	if !exists {
+		message := "target does not exist"
+		if matches := hT.documentStore.AssetCaseMatches(sitePath); len(matches) > 0 {
+			message += ", case differs from: " + strings.Join(matches, ", ")
+		}
 		hT.issueStore.AddIssue(issues.Issue{
 			Level:     issues.LevelError,
-			Message:   "target does not exist",
+			Message:   message,
 			Reference: ref,
 		})
 		return false
 	}
-	output.CheckErrorPanic(err)
 
-	if f.IsDir() {
-		f, err = os.Stat(path.Join(absPath, hT.opts.DirectoryIndex))
-		if os.IsNotExist(err) {
+	if isDir {
+		sitePath = path.Join(sitePath, hT.opts.DirectoryIndex)
+		if _, exists := hT.documentStore.ResolveAsset(sitePath); !exists {
 			hT.issueStore.AddIssue(issues.Issue{
 				Level:     issues.LevelError,
 				Message:   "target is a directory, no index",
@@ -376,6 +455,7 @@ func (hT *HTMLTest) checkFile(ref *htmldoc.Reference, absPath string) bool {
 			return false
 		}
 	}
+	hT.markReferenced(sitePath)
 	return true
 }
 
@@ -469,6 +469,13 @@ func TestAnchorInternalCaseMismatch(t *testing.T) {
 	tExpectIssueCount(t, hT, 0)
 }
 
+func TestAnchorInternalFileCaseMismatch(t *testing.T) {
+	// fails for files differing in case, suggesting the real name
+	hT := tTestFile("fixtures/links/linkCaseMismatch.html")
+	tExpectIssueCount(t, hT, 1)
+	tExpectIssue(t, hT, "target does not exist, case differs from: /gpl.png", 1)
+}
+
 func TestAnchorInternalHashBlankDefault(t *testing.T) {
 	// fails for href="#" when not asked
 	hT := tTestFile("fixtures/links/hash_href.html")
@@ -664,6 +671,22 @@ func TestAnchorNameIgnore(t *testing.T) {
 	tExpectIssueCount(t, hT, 0)
 }
 
+func TestAnchorHashSpec(t *testing.T) {
+	// fragments match ids and <a name>, not name on other elements
+	hT := tTestFile("fixtures/links/fragmentsSpec.html")
+	tExpectIssueCount(t, hT, 1)
+	tExpectIssue(t, hT, "hash does not exist", 1)
+	tExpectIssue(t, hT, "legacy anchor", 0)
+}
+
+func TestAnchorLegacyNameWarning(t *testing.T) {
+	// optionally warns about <a name> anchors
+	hT := tTestFileOpts("fixtures/links/fragmentsSpec.html",
+		map[string]interface{}{"WarnLegacyAnchors": true})
+	tExpectIssueCount(t, hT, 1)
+	tExpectIssue(t, hT, "legacy anchor <a name=\"legacy\">, use an id instead", 1)
+}
+
 func TestAnchorIdIgnore(t *testing.T) {
 	// ignores placeholder with id
 	hT := tTestFile("fixtures/links/placeholder_with_id.html")
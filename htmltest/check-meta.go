@@ -35,6 +35,7 @@ func (hT *HTMLTest) checkMetaRefresh(document *htmldoc.Document, node *html.Node
 					Level:    issues.LevelError,
 					Message:  "url in meta refresh must not start with single or double quote",
 					Document: document,
+					Check:    issues.NodeCheckName(node.Data),
 				})
 				return
 			}
@@ -47,6 +48,7 @@ func (hT *HTMLTest) checkMetaRefresh(document *htmldoc.Document, node *html.Node
 				Level:    issues.LevelError,
 				Document: document,
 				Message:  fmt.Sprintf("bad reference: %q", err),
+				Check:    issues.NodeCheckName(node.Data),
 			})
 			return
 		}
@@ -0,0 +1,239 @@
+package htmltest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/wjdp/htmltest/htmldoc"
+	"github.com/wjdp/htmltest/issues"
+)
+
+// paginationLinks : resolved rel=next/prev targets of a single document, by
+// SitePath.
+type paginationLinks struct {
+	next []string
+	prev []string
+}
+
+// Site level check, run once all documents have been tested. Groups documents
+// joined by rel=next/prev into sequences and reports each broken sequence as a
+// single issue.
+func (hT *HTMLTest) checkPagination() {
+	links := hT.paginationCollect()
+	if len(links) == 0 {
+		return
+	}
+
+	// Union-find over both directions to group documents into sequences
+	parent := make(map[string]string)
+	var find func(p string) string
+	find = func(p string) string {
+		if _, ok := parent[p]; !ok {
+			parent[p] = p
+		}
+		if parent[p] != p {
+			parent[p] = find(parent[p])
+		}
+		return parent[p]
+	}
+	for p, l := range links {
+		for _, t := range append(append([]string{}, l.next...), l.prev...) {
+			parent[find(t)] = find(p)
+		}
+	}
+
+	sequences := make(map[string][]string)
+	for p := range parent {
+		root := find(p)
+		sequences[root] = append(sequences[root], p)
+	}
+	roots := make([]string, 0, len(sequences))
+	for root, members := range sequences {
+		sort.Strings(members)
+		roots = append(roots, root)
+	}
+	sort.Slice(roots, func(i, j int) bool {
+		return sequences[roots[i]][0] < sequences[roots[j]][0]
+	})
+
+	for _, root := range roots {
+		members := sequences[root]
+		problems := paginationProblems(members, links)
+		if len(problems) == 0 {
+			continue
+		}
+		// Spans several documents so isn't attributed to any one of them
+		order := paginationOrder(members, links)
+		hT.issueStore.AddIssue(issues.Issue{
+			Level: issues.LevelError,
+			Message: fmt.Sprintf("broken pagination sequence %s: %s",
+				strings.Join(order, " → "), strings.Join(problems, "; ")),
+			Check: "Pagination",
+		})
+	}
+}
+
+// Collect resolved rel=next/prev targets of every document that has any.
+// Targets which don't resolve to a document are left to checkLink.
+func (hT *HTMLTest) paginationCollect() map[string]*paginationLinks {
+	links := make(map[string]*paginationLinks)
+	for _, document := range hT.documentStore.Documents {
+		document.Parse()
+		for _, n := range document.NodesOfInterest {
+			if n.Data != "a" && n.Data != "link" {
+				continue
+			}
+			attrs := htmldoc.ExtractAttrs(n.Attr, []string{"href", "rel"})
+			isNext, isPrev := relContains(attrs["rel"], "next"), relContains(attrs["rel"], "prev") ||
+				relContains(attrs["rel"], "previous")
+			if !isNext && !isPrev {
+				continue
+			}
+			ref, err := htmldoc.NewReference(document, n, attrs["href"])
+			if err != nil || ref.Scheme() != "file" {
+				continue
+			}
+			target, ok := hT.documentStore.ResolveRef(ref)
+			if !ok {
+				continue
+			}
+			l, ok := links[document.SitePath]
+			if !ok {
+				l = &paginationLinks{}
+				links[document.SitePath] = l
+			}
+			if isNext {
+				l.next = appendUnique(l.next, target.SitePath)
+			}
+			if isPrev {
+				l.prev = appendUnique(l.prev, target.SitePath)
+			}
+		}
+	}
+	return links
+}
+
+// List the problems with a sequence, empty if the sequence is sound.
+func paginationProblems(members []string, links map[string]*paginationLinks) []string {
+	problems := make([]string, 0)
+	get := func(p string) *paginationLinks {
+		if l, ok := links[p]; ok {
+			return l
+		}
+		return &paginationLinks{}
+	}
+
+	nextOf := make(map[string][]string)
+	for _, a := range members {
+		l := get(a)
+		if len(l.next) > 1 {
+			problems = append(problems, fmt.Sprintf("%s forks with rel=next to %s", a, strings.Join(l.next, ", ")))
+		}
+		if len(l.prev) > 1 {
+			problems = append(problems, fmt.Sprintf("%s forks with rel=prev to %s", a, strings.Join(l.prev, ", ")))
+		}
+		for _, b := range l.next {
+			nextOf[b] = append(nextOf[b], a)
+			if !inStrings(get(b).prev, a) {
+				problems = append(problems, paginationGap(b, "prev", a, get(b).prev))
+			}
+		}
+		for _, b := range l.prev {
+			if !inStrings(get(b).next, a) {
+				problems = append(problems, paginationGap(b, "next", a, get(b).next))
+			}
+		}
+	}
+	for _, b := range members {
+		if len(nextOf[b]) > 1 {
+			problems = append(problems, fmt.Sprintf("%s is rel=next of %s", b, strings.Join(nextOf[b], ", ")))
+		}
+	}
+
+	// Follow rel=next from each member looking for a way back
+	for _, start := range members {
+		seen := map[string]bool{start: true}
+		path := []string{start}
+		for p := start; len(get(p).next) > 0; {
+			p = get(p).next[0]
+			path = append(path, p)
+			if p == start {
+				problems = append(problems, "cycle "+strings.Join(path, " → "))
+				return problems
+			}
+			if seen[p] {
+				break
+			}
+			seen[p] = true
+		}
+	}
+	return problems
+}
+
+// Describe a missing or mismatched reciprocal link.
+func paginationGap(doc string, rel string, expected string, actual []string) string {
+	if len(actual) == 0 {
+		return fmt.Sprintf("%s has no rel=%s back to %s", doc, rel, expected)
+	}
+	return fmt.Sprintf("%s rel=%s points to %s, not %s", doc, rel, strings.Join(actual, ", "), expected)
+}
+
+// Best effort ordering of a sequence for display, following rel=next from the
+// first page without a predecessor. Unreached members are appended sorted.
+func paginationOrder(members []string, links map[string]*paginationLinks) []string {
+	hasPredecessor := make(map[string]bool)
+	for _, a := range members {
+		if l, ok := links[a]; ok {
+			for _, b := range l.next {
+				hasPredecessor[b] = true
+			}
+			if len(l.prev) > 0 {
+				hasPredecessor[a] = true
+			}
+		}
+	}
+	head := members[0]
+	for _, a := range members {
+		if !hasPredecessor[a] {
+			head = a
+			break
+		}
+	}
+
+	order := make([]string, 0, len(members))
+	seen := make(map[string]bool)
+	for p := head; !seen[p]; {
+		order = append(order, p)
+		seen[p] = true
+		l, ok := links[p]
+		if !ok || len(l.next) == 0 {
+			break
+		}
+		p = l.next[0]
+	}
+	for _, a := range members {
+		if !seen[a] {
+			order = append(order, a)
+		}
+	}
+	return order
+}
+
+// Append s to list if it isn't already present.
+func appendUnique(list []string, s string) []string {
+	if inStrings(list, s) {
+		return list
+	}
+	return append(list, s)
+}
+
+// Is s present in list?
+func inStrings(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
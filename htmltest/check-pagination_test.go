@@ -0,0 +1,50 @@
+package htmltest
+
+import (
+	"testing"
+)
+
+func TestPaginationValid(t *testing.T) {
+	// passes for a reciprocal sequence using both link and a tags
+	hT := tTestDirectoryOpts("fixtures/pagination/ok",
+		map[string]interface{}{"CheckPagination": true})
+	tExpectIssueCount(t, hT, 0)
+}
+
+func TestPaginationDisabledByDefault(t *testing.T) {
+	hT := tTestDirectory("fixtures/pagination/gap")
+	tExpectIssueCount(t, hT, 0)
+}
+
+func TestPaginationGap(t *testing.T) {
+	// fails for a page missing the rel=prev back to its predecessor
+	hT := tTestDirectoryOpts("fixtures/pagination/gap",
+		map[string]interface{}{"CheckPagination": true})
+	tExpectIssueCount(t, hT, 1)
+	tExpectIssue(t, hT, "broken pagination sequence 1.html → 2.html → 3.html: 2.html has no rel=prev back to 1.html", 1)
+}
+
+func TestPaginationCycle(t *testing.T) {
+	// fails for a sequence which loops back on itself
+	hT := tTestDirectoryOpts("fixtures/pagination/cycle",
+		map[string]interface{}{"CheckPagination": true})
+	tExpectIssueCount(t, hT, 1)
+	tExpectIssue(t, hT, "cycle a.html → b.html → c.html → a.html", 1)
+}
+
+func TestPaginationFork(t *testing.T) {
+	// fails for a page with two different next pages
+	hT := tTestDirectoryOpts("fixtures/pagination/fork",
+		map[string]interface{}{"CheckPagination": true})
+	tExpectIssueCount(t, hT, 1)
+	tExpectIssue(t, hT, "1.html forks with rel=next to 2a.html, 2b.html", 1)
+}
+
+func TestPaginationMismatch(t *testing.T) {
+	// fails for a prev pointing at the wrong page, joining two sequences
+	hT := tTestDirectoryOpts("fixtures/pagination/mismatch",
+		map[string]interface{}{"CheckPagination": true})
+	tExpectIssueCount(t, hT, 1)
+	tExpectIssue(t, hT, "b2.html rel=prev points to a1.html, not b1.html", 1)
+	tExpectIssue(t, hT, "a1.html rel=next points to a2.html, not b2.html", 1)
+}
@@ -0,0 +1,191 @@
+package htmltest
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/wjdp/htmltest/htmldoc"
+	"github.com/wjdp/htmltest/issues"
+	"github.com/wjdp/htmltest/pdfdoc"
+	"golang.org/x/net/html"
+)
+
+// pdfResult : a PDF as read by pdfdoc, err set if it couldn't be
+type pdfResult struct {
+	doc *pdfdoc.Document
+	err error
+}
+
+// Stands in for the element references in PDFs hang off, checks expect one
+// to be present
+var pdfNode = &html.Node{Type: html.ElementNode, Data: "pdf"}
+
+// Is sitePath a PDF we should read?
+func (hT *HTMLTest) isPDF(sitePath string) bool {
+	return hT.opts.CheckPDFs && strings.EqualFold(path.Ext(sitePath), ".pdf")
+}
+
+// Read the PDF at sitePath, once. Every PDF read is checked by checkPDFs.
+func (hT *HTMLTest) pdfInspect(sitePath string) *pdfResult {
+	sitePath = strings.TrimPrefix(path.Clean("/"+sitePath), "/")
+	hT.pdfMutex.Lock()
+	defer hT.pdfMutex.Unlock()
+
+	if hT.pdfCache == nil {
+		hT.pdfCache = make(map[string]*pdfResult)
+	}
+	if result, ok := hT.pdfCache[sitePath]; ok {
+		return result
+	}
+	doc, err := pdfdoc.Open(path.Join(hT.documentStore.BasePath, sitePath))
+	result := &pdfResult{doc: doc, err: err}
+	hT.pdfCache[sitePath] = result
+	return result
+}
+
+// Check the fragment of a reference to a PDF which exists. Viewers accept
+// parameters such as #page=2&zoom=50 and #nameddest=intro, or a bare
+// destination name. Parameters other than page and nameddest are ignored.
+func (hT *HTMLTest) checkPDFFragment(ref *htmldoc.Reference, sitePath string) {
+	result := hT.pdfInspect(sitePath)
+	if result.err != nil {
+		// Reported when the PDF itself is checked
+		return
+	}
+	for _, param := range strings.Split(ref.URL.Fragment, "&") {
+		key, value := "nameddest", param
+		if i := strings.Index(param, "="); i >= 0 {
+			key, value = param[:i], param[i+1:]
+		}
+		switch key {
+		case "nameddest":
+			if value, _ = url.PathUnescape(value); !result.doc.HasDest(value) {
+				hT.issueStore.AddIssue(issues.Issue{
+					Level:     issues.LevelError,
+					Message:   "named destination does not exist in PDF",
+					Reference: ref,
+					Check:     "PDFs",
+				})
+			}
+		case "page":
+			if page, err := strconv.Atoi(value); err != nil || page < 1 || page > result.doc.Pages {
+				hT.issueStore.AddIssue(issues.Issue{
+					Level:     issues.LevelError,
+					Message:   fmt.Sprintf("page does not exist, PDF has %d pages", result.doc.Pages),
+					Reference: ref,
+					Check:     "PDFs",
+				})
+			}
+		}
+	}
+}
+
+// Site level check, run once all documents have been tested. Checks the
+// links of every PDF referenced by documents, along with those matching
+// PDFFiles, issues are attributed to the PDF and page.
+func (hT *HTMLTest) checkPDFs() {
+	if !hT.opts.CheckPDFs {
+		return
+	}
+
+	globs := make([]*regexp.Regexp, len(hT.opts.PDFFiles))
+	for i, glob := range hT.opts.PDFFiles {
+		globs[i] = globToRegexp(fmt.Sprint(glob))
+	}
+	for _, sitePath := range hT.documentStore.AssetFiles() {
+		if !hT.isPDF(sitePath) {
+			continue
+		}
+		for _, globRegexp := range globs {
+			if globRegexp.MatchString(sitePath) {
+				hT.pdfInspect(sitePath)
+				break
+			}
+		}
+	}
+
+	// Links in PDFs may lead to more of them
+	checked := make(map[string]bool)
+	for pending := hT.pdfPending(checked); len(pending) > 0; pending = hT.pdfPending(checked) {
+		for _, sitePath := range pending {
+			checked[sitePath] = true
+			if hT.opts.OnlyFailed && !hT.previousFailures.hasDocument(sitePath) {
+				continue
+			}
+			hT.checkPDF(sitePath)
+		}
+	}
+}
+
+// Sorted site paths of PDFs read but not yet checked.
+func (hT *HTMLTest) pdfPending(checked map[string]bool) []string {
+	hT.pdfMutex.Lock()
+	defer hT.pdfMutex.Unlock()
+	pending := make([]string, 0)
+	for sitePath := range hT.pdfCache {
+		if !checked[sitePath] {
+			pending = append(pending, sitePath)
+		}
+	}
+	sort.Strings(pending)
+	return pending
+}
+
+// Check the links in a single PDF.
+func (hT *HTMLTest) checkPDF(sitePath string) {
+	document := &htmldoc.Document{
+		FilePath: path.Join(hT.documentStore.BasePath, sitePath),
+		SitePath: sitePath,
+		BasePath: path.Dir(sitePath),
+	}
+	document.Init()
+	defer hT.issueStore.PrintDocumentIssues(document)
+
+	hT.issueStore.AddIssue(issues.Issue{
+		Level:   issues.LevelDebug,
+		Message: "checkPDF on " + sitePath,
+		Check:   "PDFs",
+	})
+
+	result := hT.pdfInspect(sitePath)
+	if result.err == pdfdoc.ErrEncrypted {
+		hT.issueStore.AddIssue(issues.Issue{
+			Level:    issues.LevelWarning,
+			Document: document,
+			Message:  "encrypted PDF, links not checked",
+			Check:    "PDFs",
+		})
+		return
+	} else if result.err != nil {
+		hT.issueStore.AddIssue(issues.Issue{
+			Level:    issues.LevelError,
+			Document: document,
+			Message:  fmt.Sprintf("cannot read PDF: %s", result.err),
+			Check:    "PDFs",
+		})
+		return
+	}
+
+	for _, link := range result.doc.Links {
+		field := fmt.Sprintf("page %d", link.Page)
+		if link.Dest != "" && !result.doc.HasDest(link.Dest) {
+			ref, _ := htmldoc.NewReference(document, pdfNode, "#"+link.Dest)
+			if ref != nil {
+				ref.Field = field
+			}
+			hT.issueStore.AddIssue(issues.Issue{
+				Level:     issues.LevelError,
+				Document:  document,
+				Message:   "named destination does not exist in PDF",
+				Reference: ref,
+				Check:     "PDFs",
+			})
+		}
+		hT.checkEmbeddedURL("PDFs", document, pdfNode, field, strings.TrimSpace(link.URI))
+	}
+}
@@ -0,0 +1,44 @@
+package htmltest
+
+import (
+	"testing"
+)
+
+func TestPDFDisabled(t *testing.T) {
+	hT := tTestDirectory("fixtures/pdf")
+	tExpectIssueCount(t, hT, 0)
+}
+
+func TestPDFFragments(t *testing.T) {
+	hT := tTestDirectoryOpts("fixtures/pdf", map[string]interface{}{"CheckPDFs": true})
+	tExpectIssue(t, hT, "page does not exist, PDF has 2 pages", 1)
+	// #appendix in index.html, summary in other.pdf and gone in guide.pdf
+	tExpectIssue(t, hT, "named destination does not exist in PDF", 3)
+}
+
+func TestPDFLinks(t *testing.T) {
+	hT := tTestDirectoryOpts("fixtures/pdf", map[string]interface{}{"CheckPDFs": true})
+	tExpectIssue(t, hT, "target does not exist", 1)
+	tExpectIssue(t, hT, "cannot read PDF", 1)
+	tExpectIssue(t, hT, "checkPDF on downloads/guide.pdf", 1)
+	// Reached through the GoToR link in guide.pdf
+	tExpectIssue(t, hT, "checkPDF on downloads/other.pdf", 1)
+	tExpectIssue(t, hT, "checkPDF on downloads/archive/old.pdf", 0)
+	tExpectIssueCount(t, hT, 6)
+}
+
+func TestPDFFiles(t *testing.T) {
+	hT := tTestDirectoryOpts("fixtures/pdf", map[string]interface{}{
+		"CheckPDFs": true,
+		"PDFFiles":  []interface{}{"downloads/archive/*.pdf"},
+	})
+	tExpectIssue(t, hT, "checkPDF on downloads/archive/old.pdf", 1)
+	tExpectIssue(t, hT, "target does not exist", 2)
+	tExpectIssueCount(t, hT, 7)
+}
+
+func TestPDFSingleFile(t *testing.T) {
+	hT := tTestFileOpts("fixtures/pdf/index.html", map[string]interface{}{"CheckPDFs": true})
+	tExpectIssue(t, hT, "checkPDF on downloads/guide.pdf", 1)
+	tExpectIssueCount(t, hT, 6)
+}
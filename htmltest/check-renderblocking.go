@@ -0,0 +1,186 @@
+package htmltest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/wjdp/htmltest/htmldoc"
+	"github.com/wjdp/htmltest/issues"
+	"golang.org/x/net/html"
+)
+
+// Matches @import rules, capturing the URL whether quoted or in url()
+var cssImportRegexp = regexp.MustCompile(`@import\s+(?:url\(\s*)?["']?([^"'\s);]+)`)
+
+// Matches CSS comments, removed before looking for @import
+var cssCommentRegexp = regexp.MustCompile(`(?s)/\*.*?\*/`)
+
+// Performance lint for resources which hold up the first render: scripts in
+// the head which aren't deferred, stylesheets in the body or too many in the
+// head, @import chains and large inline scripts and styles.
+func (hT *HTMLTest) checkRenderBlocking(document *htmldoc.Document) {
+	stylesheets := 0
+	document.Walk(func(n *html.Node) {
+		if n.Type != html.ElementNode {
+			return
+		}
+		attrs := htmldoc.ExtractAttrs(n.Attr, []string{"src", "href", "rel", "type", "media"})
+		switch n.Data {
+		case "script":
+			if !isClassicScript(attrs["type"]) {
+				return
+			}
+			if !htmldoc.AttrPresent(n.Attr, "src") {
+				if size := len(nodeText(n)); hT.opts.MaxInlineScriptBytes > 0 && size > hT.opts.MaxInlineScriptBytes {
+					hT.addNodeIssue("Render blocking", issues.LevelWarning, document, n, "",
+						fmt.Sprintf("inline script is %d bytes, more than %d", size, hT.opts.MaxInlineScriptBytes))
+				}
+				return
+			}
+			if inHead(n) && !htmldoc.AttrPresent(n.Attr, "defer") && !htmldoc.AttrPresent(n.Attr, "async") {
+				hT.addNodeIssue("Render blocking", issues.LevelWarning, document, n, attrs["src"],
+					"render-blocking script in head, add defer or async")
+			}
+		case "link":
+			if !relContains(attrs["rel"], "stylesheet") || relContains(attrs["rel"], "alternate") {
+				return
+			}
+			if !inHead(n) {
+				hT.addNodeIssue("Render blocking", issues.LevelWarning, document, n, attrs["href"],
+					"stylesheet loaded in body, move it to the head")
+			} else if strings.TrimSpace(strings.ToLower(attrs["media"])) != "print" {
+				stylesheets++
+			}
+			ref, err := htmldoc.NewReference(document, n, attrs["href"])
+			if err == nil && ref.Scheme() == "file" {
+				hT.checkCSSImports(document, n, attrs["href"], "stylesheet",
+					hT.cssImportChain(ref.RefSitePath(), map[string]bool{}))
+			}
+		case "style":
+			text := nodeText(n)
+			if size := len(text); hT.opts.MaxInlineStyleBytes > 0 && size > hT.opts.MaxInlineStyleBytes {
+				hT.addNodeIssue("Render blocking", issues.LevelWarning, document, n, "",
+					fmt.Sprintf("inline style is %d bytes, more than %d", size, hT.opts.MaxInlineStyleBytes))
+			}
+			chain := []string{}
+			for _, imp := range cssImportURLs(text, document.BasePath) {
+				if c := hT.cssImportLink(imp, map[string]bool{}); len(c) > len(chain) {
+					chain = c
+				}
+			}
+			hT.checkCSSImports(document, n, "", "inline style", chain)
+		}
+	})
+
+	if hT.opts.MaxBlockingStylesheets > 0 && stylesheets > hT.opts.MaxBlockingStylesheets {
+		hT.issueStore.AddIssue(issues.Issue{
+			Level:    issues.LevelWarning,
+			Document: document,
+			Message: fmt.Sprintf("%d render-blocking stylesheets, more than %d, combine them",
+				stylesheets, hT.opts.MaxBlockingStylesheets),
+			Check: "Render blocking",
+		})
+	}
+}
+
+// Report an @import chain found in a stylesheet or inline style.
+func (hT *HTMLTest) checkCSSImports(document *htmldoc.Document, node *html.Node, urlStr string, what string,
+	chain []string) {
+	if len(chain) == 0 {
+		return
+	}
+	hT.addNodeIssue("Render blocking", issues.LevelWarning, document, node, urlStr,
+		fmt.Sprintf("%s @imports %s, each import delays rendering", what, strings.Join(chain, " → ")))
+}
+
+// Is a script of the given type run as soon as it's parsed? Modules are
+// deferred and other types, such as JSON data, aren't run at all.
+func isClassicScript(scriptType string) bool {
+	scriptType = strings.ToLower(strings.TrimSpace(scriptType))
+	return scriptType == "" || strings.Contains(scriptType, "javascript") || strings.Contains(scriptType, "ecmascript")
+}
+
+// Is n inside the document's head?
+func inHead(n *html.Node) bool {
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.Type == html.ElementNode && (p.Data == "head" || p.Data == "body") {
+			return p.Data == "head"
+		}
+	}
+	return false
+}
+
+// Text content of n's children, such as an inline script.
+func nodeText(n *html.Node) string {
+	var sb strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.TextNode {
+			sb.WriteString(c.Data)
+		}
+	}
+	return sb.String()
+}
+
+// URLs a stylesheet @imports, local ones resolved to site paths against
+// basePath.
+func cssImportURLs(css string, basePath string) []string {
+	imports := make([]string, 0)
+	for _, m := range cssImportRegexp.FindAllStringSubmatch(cssCommentRegexp.ReplaceAllString(css, ""), -1) {
+		u, err := url.Parse(m[1])
+		if err != nil || u.Scheme != "" || u.Host != "" {
+			imports = append(imports, m[1])
+			continue
+		}
+		if !strings.HasPrefix(u.Path, "/") {
+			u.Path = path.Join(basePath, u.Path)
+		}
+		imports = append(imports, strings.TrimPrefix(path.Clean("/"+u.Path), "/"))
+	}
+	return imports
+}
+
+// The @imports of the local stylesheet at sitePath, read once. sitePath has
+// no leading slash.
+func (hT *HTMLTest) cssImports(sitePath string) []string {
+	hT.cssImportsMutex.Lock()
+	defer hT.cssImportsMutex.Unlock()
+
+	if hT.cssImportsCache == nil {
+		hT.cssImportsCache = make(map[string][]string)
+	}
+	if imports, ok := hT.cssImportsCache[sitePath]; ok {
+		return imports
+	}
+	// Missing stylesheets are reported by checkLink
+	b, _ := ioutil.ReadFile(path.Join(hT.opts.DirectoryPath, sitePath))
+	imports := cssImportURLs(string(b), path.Dir(sitePath))
+	hT.cssImportsCache[sitePath] = imports
+	return imports
+}
+
+// The longest chain of @imports starting from the local stylesheet at
+// sitePath. seen guards against cycles.
+func (hT *HTMLTest) cssImportChain(sitePath string, seen map[string]bool) []string {
+	sitePath = strings.TrimPrefix(path.Clean("/"+sitePath), "/")
+	chain := []string{}
+	seen[sitePath] = true
+	for _, imp := range hT.cssImports(sitePath) {
+		if c := hT.cssImportLink(imp, seen); len(c) > len(chain) {
+			chain = c
+		}
+	}
+	delete(seen, sitePath)
+	return chain
+}
+
+// The chain starting with a single @import, which is followed if it's local.
+func (hT *HTMLTest) cssImportLink(imp string, seen map[string]bool) []string {
+	if strings.Contains(imp, ":") || strings.HasPrefix(imp, "//") || seen[imp] {
+		return []string{imp}
+	}
+	return append([]string{imp}, hT.cssImportChain(imp, seen)...)
+}
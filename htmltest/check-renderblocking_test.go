@@ -0,0 +1,67 @@
+package htmltest
+
+import (
+	"github.com/daviddengcn/go-assert"
+	"testing"
+)
+
+func TestRenderBlockingDisabled(t *testing.T) {
+	hT := tTestFile("fixtures/renderblocking/blocking.html")
+	tExpectIssue(t, hT, "render-blocking", 0)
+}
+
+func TestRenderBlockingScripts(t *testing.T) {
+	hT := tTestFileOpts("fixtures/renderblocking/blocking.html",
+		map[string]interface{}{"CheckRenderBlocking": true})
+	// Not deferred, async, a module, data or in the body
+	tExpectIssue(t, hT, "render-blocking script in head, add defer or async", 1)
+	tExpectIssueCount(t, hT, 0)
+}
+
+func TestRenderBlockingStylesheets(t *testing.T) {
+	hT := tTestFileOpts("fixtures/renderblocking/blocking.html",
+		map[string]interface{}{"CheckRenderBlocking": true})
+	tExpectIssue(t, hT, "stylesheet loaded in body, move it to the head", 1)
+	// Print and alternate stylesheets don't count
+	tExpectIssue(t, hT, "3 render-blocking stylesheets", 0)
+	hT = tTestFileOpts("fixtures/renderblocking/blocking.html",
+		map[string]interface{}{"CheckRenderBlocking": true, "MaxBlockingStylesheets": 2})
+	tExpectIssue(t, hT, "3 render-blocking stylesheets, more than 2, combine them", 1)
+}
+
+func TestRenderBlockingImports(t *testing.T) {
+	hT := tTestFileOpts("fixtures/renderblocking/blocking.html",
+		map[string]interface{}{"CheckRenderBlocking": true})
+	// Longest chain only, the commented import is skipped
+	tExpectIssue(t, hT, "stylesheet @imports css/a.css → css/b.css, each import delays rendering", 1)
+	tExpectIssue(t, hT, "stylesheet @imports css/b.css, each import delays rendering", 1)
+	tExpectIssue(t, hT, "inline style @imports css/b.css", 1)
+	tExpectIssue(t, hT, "@imports", 3)
+	tExpectIssue(t, hT, "commented", 0)
+}
+
+func TestRenderBlockingInline(t *testing.T) {
+	hT := tTestFileOpts("fixtures/renderblocking/inline.html",
+		map[string]interface{}{"CheckRenderBlocking": true})
+	tExpectIssue(t, hT, "inline script is 22003 bytes, more than 14336", 1)
+	tExpectIssue(t, hT, "inline style is 17003 bytes, more than 14336", 1)
+	hT = tTestFileOpts("fixtures/renderblocking/inline.html",
+		map[string]interface{}{"CheckRenderBlocking": true, "MaxInlineScriptBytes": 0, "MaxInlineStyleBytes": 0})
+	tExpectIssue(t, hT, "bytes, more than", 0)
+}
+
+func TestRenderBlockingFast(t *testing.T) {
+	hT := tTestFileOpts("fixtures/renderblocking/fast.html",
+		map[string]interface{}{"CheckRenderBlocking": true})
+	tExpectIssue(t, hT, "", 1) // testDocument only
+}
+
+func TestCSSImportURLs(t *testing.T) {
+	imports := cssImportURLs(`/* @import "x.css"; */
+@import "a.css";
+@import url('../b.css?v=2') screen;
+@import url(/c.css);
+@import "//cdn.example.com/d.css";`, "css/theme")
+	assert.StringEquals(t, "imports", imports,
+		[]string{"css/theme/a.css", "css/b.css", "c.css", "//cdn.example.com/d.css"})
+}
@@ -18,6 +18,7 @@ func (hT *HTMLTest) checkScript(document *htmldoc.Document, node *html.Node) {
 			Level:    issues.LevelError,
 			Document: document,
 			Message:  fmt.Sprintf("bad reference: %q", err),
+			Check:    issues.NodeCheckName(node.Data),
 		})
 		return
 	}
@@ -52,5 +53,6 @@ func (hT *HTMLTest) checkScript(document *htmldoc.Document, node *html.Node) {
 		hT.checkExternal(ref)
 	case "file":
 		hT.checkInternal(ref)
+		hT.checkSourceMap(ref)
 	}
 }
@@ -0,0 +1,148 @@
+package htmltest
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"github.com/wjdp/htmltest/htmldoc"
+	"github.com/wjdp/htmltest/issues"
+	"golang.org/x/net/html"
+)
+
+// secretPattern : a named regex describing one kind of secret
+type secretPattern struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// Built in secret formats, user supplied SecretPatterns are appended to these
+var defaultSecretPatterns = []secretPattern{
+	{"AWS access key", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"Google API key", regexp.MustCompile(`\bAIza[0-9A-Za-z_\-]{35}\b`)},
+	{"GitHub token", regexp.MustCompile(`\bgh[pousr]_[0-9A-Za-z]{36,}\b`)},
+	{"Slack token", regexp.MustCompile(`\bxox[abposr]-[0-9A-Za-z\-]{10,}\b`)},
+	{"Stripe secret key", regexp.MustCompile(`\b[rs]k_live_[0-9A-Za-z]{16,}\b`)},
+	{"private key", regexp.MustCompile(`-----BEGIN (?:[A-Z]+ )?PRIVATE KEY(?: BLOCK)?-----`)},
+	{"JSON web token", regexp.MustCompile(`\beyJ[0-9A-Za-z_\-]{8,}\.eyJ[0-9A-Za-z_\-]{8,}\.[0-9A-Za-z_\-]{8,}`)},
+}
+
+// Matches email addresses, the domain is captured for SecretEmailDomains
+var secretEmailRegexp = regexp.MustCompile(`\b[A-Za-z0-9._%+\-]+@([A-Za-z0-9.\-]+\.[A-Za-z]{2,})\b`)
+
+// Scan inline scripts, comments and attribute values of a document for
+// secrets and internal email addresses.
+func (hT *HTMLTest) checkSecrets(document *htmldoc.Document) {
+	patterns := hT.secretPatterns()
+
+	// Raw source is used to report positions, the parsed tree to find content
+	raw, err := ioutil.ReadFile(document.FilePath)
+	if err != nil {
+		raw = []byte{}
+	}
+	// Where to resume searching raw for each value, repeated values map to
+	// successive occurrences
+	offsets := make(map[string]int)
+
+	scan := func(text string, where string) {
+		for _, p := range patterns {
+			for _, match := range p.re.FindAllString(text, -1) {
+				hT.addSecretIssue(document, raw, offsets, p.name, where, match)
+			}
+		}
+		for _, m := range secretEmailRegexp.FindAllStringSubmatch(text, -1) {
+			if hT.isSecretEmailDomain(m[1]) {
+				hT.addSecretIssue(document, raw, offsets, "internal email address", where, m[0])
+			}
+		}
+	}
+
+	document.Walk(func(n *html.Node) {
+		switch n.Type {
+		case html.CommentNode:
+			scan(n.Data, "comment")
+		case html.ElementNode:
+			for _, attr := range n.Attr {
+				scan(attr.Val, fmt.Sprintf("<%s %s>", n.Data, attr.Key))
+			}
+			if n.Data == "script" && !htmldoc.AttrPresent(n.Attr, "src") {
+				for c := n.FirstChild; c != nil; c = c.NextSibling {
+					if c.Type == html.TextNode {
+						scan(c.Data, "inline script")
+					}
+				}
+			}
+		}
+	})
+}
+
+// Raise a single secret issue, locating the value in the raw source.
+func (hT *HTMLTest) addSecretIssue(document *htmldoc.Document, raw []byte, offsets map[string]int,
+	name string, where string, value string) {
+	position := ""
+	if i := bytes.Index(raw[offsets[value]:], []byte(value)); i >= 0 {
+		i += offsets[value]
+		offsets[value] = i + len(value)
+		line := bytes.Count(raw[:i], []byte("\n")) + 1
+		col := i - bytes.LastIndex(raw[:i], []byte("\n"))
+		position = fmt.Sprintf(" at %d:%d", line, col)
+	}
+
+	hT.issueStore.AddIssue(issues.Issue{
+		Level:    issues.LevelError,
+		Message:  fmt.Sprintf("possible %s in %s%s: %s", name, where, position, redactSecret(value)),
+		Document: document,
+		Check:    "Secrets",
+	})
+}
+
+// Is domain, or a parent of it, listed in SecretEmailDomains?
+func (hT *HTMLTest) isSecretEmailDomain(domain string) bool {
+	domain = strings.ToLower(domain)
+	for _, item := range hT.opts.SecretEmailDomains {
+		d := strings.ToLower(fmt.Sprintf("%v", item))
+		if domain == d || strings.HasSuffix(domain, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// Built in patterns plus compiled SecretPatterns, invalid user regexes are
+// reported once and skipped.
+func (hT *HTMLTest) secretPatterns() []secretPattern {
+	hT.secretPatternsOnce.Do(func() {
+		hT.secretPatternsCompiled = append([]secretPattern{}, defaultSecretPatterns...)
+		for _, item := range hT.opts.SecretPatterns {
+			re, err := regexp.Compile(fmt.Sprintf("%v", item))
+			if err != nil {
+				hT.issueStore.AddIssue(issues.Issue{
+					Level:   issues.LevelError,
+					Message: fmt.Sprintf("invalid SecretPatterns regex %q: %s", item, err),
+					Check:   "Secrets",
+				})
+				continue
+			}
+			hT.secretPatternsCompiled = append(hT.secretPatternsCompiled,
+				secretPattern{name: "secret matching " + re.String(), re: re})
+		}
+	})
+	return hT.secretPatternsCompiled
+}
+
+// Mask the middle of a secret so it isn't leaked again in logs.
+func redactSecret(value string) string {
+	if len(value) <= 2 {
+		return strings.Repeat("*", len(value))
+	}
+	if len(value) <= 8 {
+		return value[:2] + strings.Repeat("*", len(value)-2)
+	}
+	keep := 4
+	if len(value) < 16 {
+		keep = 2
+	}
+	return value[:keep] + strings.Repeat("*", len(value)-2*keep) + value[len(value)-keep:]
+}
@@ -0,0 +1,300 @@
+package htmltest
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/wjdp/htmltest/htmldoc"
+	"github.com/wjdp/htmltest/issues"
+)
+
+// Matches the last `//# sourceMappingURL=` (JS) or `/*# sourceMappingURL= */`
+// (CSS) comment in a file. The legacy `@` form is also accepted.
+var sourceMapCommentRegexp = regexp.MustCompile(
+	`(?m)(?://|/\*)[#@][ \t]*sourceMappingURL=([^\s*]+)[ \t]*(?:\*/)?[ \t]*$`)
+
+// Name of the Netlify/Cloudflare style headers file read from the site root
+const headersFileName string = "_headers"
+
+// sourceMapResult : outcome of inspecting a single local JS/CSS file, cached
+// so files referenced from many documents are only read once.
+type sourceMapResult struct {
+	mapURLs []string // sourceMappingURL values, comment first then _headers
+	sibling bool     // A conventional <file>.map exists alongside the file
+}
+
+// headersRule : a path pattern from the _headers file and its SourceMap value
+type headersRule struct {
+	pattern   *regexp.Regexp
+	sourceMap string
+}
+
+// Checks source maps referenced by a local script or stylesheet reference.
+// Assumes checkInternal has already reported a missing target.
+func (hT *HTMLTest) checkSourceMap(ref *htmldoc.Reference) {
+	if !hT.opts.CheckSourceMaps && !hT.opts.ForbidSourceMaps {
+		return
+	}
+	if hT.opts.isInternalURLIgnored(ref.URLString()) {
+		return
+	}
+
+	sitePath := path.Clean("/" + ref.RefSitePath())
+	result, ok := hT.sourceMapInspect(sitePath)
+	if !ok {
+		return
+	}
+
+	if hT.opts.ForbidSourceMaps {
+		for _, mapURL := range result.mapURLs {
+			hT.issueStore.AddIssue(issues.Issue{
+				Level:     issues.LevelError,
+				Message:   "source map referenced: " + sourceMapDisplay(mapURL),
+				Reference: ref,
+				Check:     "Source maps",
+			})
+		}
+		if result.sibling && len(result.mapURLs) == 0 {
+			hT.issueStore.AddIssue(issues.Issue{
+				Level:     issues.LevelError,
+				Message:   "source map present: " + sitePath + ".map",
+				Reference: ref,
+				Check:     "Source maps",
+			})
+		}
+		return
+	}
+
+	for _, mapURL := range result.mapURLs {
+		hT.checkSourceMapURL(ref, sitePath, mapURL)
+	}
+}
+
+// Validate a single sourceMappingURL found in the file at sitePath.
+func (hT *HTMLTest) checkSourceMapURL(ref *htmldoc.Reference, sitePath string, mapURL string) {
+	if strings.HasPrefix(mapURL, "data:") {
+		if !sourceMapDataValid(mapURL) {
+			hT.issueStore.AddIssue(issues.Issue{
+				Level:     issues.LevelError,
+				Message:   "inline source map is not valid JSON",
+				Reference: ref,
+				Check:     "Source maps",
+			})
+		}
+		return
+	}
+
+	u, err := url.Parse(mapURL)
+	if err != nil {
+		hT.issueStore.AddIssue(issues.Issue{
+			Level:     issues.LevelError,
+			Message:   "bad source map reference: " + mapURL,
+			Reference: ref,
+			Check:     "Source maps",
+		})
+		return
+	}
+	if u.Scheme != "" || u.Host != "" {
+		hT.issueStore.AddIssue(issues.Issue{
+			Level:     issues.LevelDebug,
+			Message:   "skipping non-local source map: " + mapURL,
+			Reference: ref,
+			Check:     "Source maps",
+		})
+		return
+	}
+
+	// Relative map URLs resolve against the referencing file, not the document
+	mapPath := strings.TrimPrefix(u.Path, "/")
+	if !strings.HasPrefix(u.Path, "/") {
+		mapPath = path.Join(strings.TrimPrefix(path.Dir(sitePath), "/"), mapPath)
+	}
+	mapPath = path.Clean(mapPath)
+	if mapPath == ".." || strings.HasPrefix(mapPath, "../") {
+		hT.issueStore.AddIssue(issues.Issue{
+			Level:     issues.LevelError,
+			Message:   "source map is outside the site: " + mapURL,
+			Reference: ref,
+			Check:     "Source maps",
+		})
+		return
+	}
+	mapSitePath := "/" + mapPath
+
+	if isDir, exists := hT.documentStore.ResolveAsset(mapSitePath); !exists || isDir {
+		hT.issueStore.AddIssue(issues.Issue{
+			Level:     issues.LevelError,
+			Message:   "source map does not exist: " + mapSitePath,
+			Reference: ref,
+			Check:     "Source maps",
+		})
+		return
+	}
+	hT.markReferenced(mapSitePath)
+	if hT.documentStore.AssetIgnored(mapSitePath) {
+		// Exists, but the contents of IgnoreDirs aren't checked
+		return
+	}
+
+	b, err := ioutil.ReadFile(path.Join(hT.documentStore.BasePath, mapPath))
+	if err != nil {
+		hT.issueStore.AddIssue(issues.Issue{
+			Level:     issues.LevelError,
+			Message:   "source map cannot be read: " + mapSitePath,
+			Reference: ref,
+			Check:     "Source maps",
+		})
+		return
+	}
+	if !json.Valid(b) {
+		hT.issueStore.AddIssue(issues.Issue{
+			Level:     issues.LevelError,
+			Message:   "source map is not valid JSON: " + mapSitePath,
+			Reference: ref,
+			Check:     "Source maps",
+		})
+	}
+}
+
+// Read and cache the source map details of the file at the given site path.
+// Returns false if the file cannot be read. The file is read outside the
+// lock, so two checks of the same file may both read it.
+func (hT *HTMLTest) sourceMapInspect(sitePath string) (*sourceMapResult, bool) {
+	hT.headersRulesOnce.Do(func() {
+		hT.headersRules = readHeadersRules(path.Join(hT.opts.DirectoryPath, headersFileName))
+	})
+
+	hT.sourceMapMutex.Lock()
+	result, ok := hT.sourceMapCache[sitePath]
+	hT.sourceMapMutex.Unlock()
+	if ok {
+		return result, result != nil
+	}
+
+	result = hT.sourceMapRead(sitePath)
+
+	hT.sourceMapMutex.Lock()
+	if hT.sourceMapCache == nil {
+		hT.sourceMapCache = make(map[string]*sourceMapResult)
+	}
+	hT.sourceMapCache[sitePath] = result
+	hT.sourceMapMutex.Unlock()
+	return result, result != nil
+}
+
+// Find the source maps of the file at sitePath, nil if it cannot be read.
+func (hT *HTMLTest) sourceMapRead(sitePath string) *sourceMapResult {
+	b, err := ioutil.ReadFile(path.Join(hT.opts.DirectoryPath, sitePath))
+	if err != nil {
+		return nil
+	}
+
+	result := &sourceMapResult{mapURLs: make([]string, 0)}
+	if matches := sourceMapCommentRegexp.FindAllSubmatch(b, -1); len(matches) > 0 {
+		// Only the last comment is honoured by browsers
+		result.mapURLs = append(result.mapURLs, string(matches[len(matches)-1][1]))
+	}
+	for _, rule := range hT.headersRules {
+		if rule.pattern.MatchString(sitePath) {
+			result.mapURLs = append(result.mapURLs, rule.sourceMap)
+		}
+	}
+	if _, exists := hT.documentStore.ResolveAsset(sitePath + ".map"); exists {
+		result.sibling = true
+	}
+	return result
+}
+
+// Parse SourceMap/X-SourceMap entries from a _headers file. A missing file
+// yields no rules.
+func readHeadersRules(filePath string) []headersRule {
+	rules := make([]headersRule, 0)
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		return rules
+	}
+	defer f.Close()
+
+	var pattern *regexp.Regexp
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if line[0] != ' ' && line[0] != '\t' {
+			// Unindented lines open a new path block
+			pattern = headersPathPattern(trimmed)
+			continue
+		}
+		if pattern == nil {
+			continue
+		}
+		parts := strings.SplitN(trimmed, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(parts[0]))
+		if key == "sourcemap" || key == "x-sourcemap" {
+			rules = append(rules, headersRule{
+				pattern:   pattern,
+				sourceMap: strings.TrimSpace(parts[1]),
+			})
+		}
+	}
+	return rules
+}
+
+// Convert a _headers path, with `*` splats and `:placeholder` segments, to a
+// regexp.
+func headersPathPattern(p string) *regexp.Regexp {
+	segments := strings.Split(p, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			segments[i] = "[^/]+"
+			continue
+		}
+		segments[i] = strings.Replace(regexp.QuoteMeta(segment), `\*`, ".*", -1)
+	}
+	return regexp.MustCompile("^" + strings.Join(segments, "/") + "$")
+}
+
+// Does an inline data: source map decode to valid JSON?
+func sourceMapDataValid(dataURL string) bool {
+	parts := strings.SplitN(strings.TrimPrefix(dataURL, "data:"), ",", 2)
+	if len(parts) != 2 {
+		return false
+	}
+	var payload []byte
+	if strings.HasSuffix(parts[0], ";base64") {
+		b, err := base64.StdEncoding.DecodeString(parts[1])
+		if err != nil {
+			return false
+		}
+		payload = b
+	} else {
+		s, err := url.PathUnescape(parts[1])
+		if err != nil {
+			return false
+		}
+		payload = []byte(s)
+	}
+	return json.Valid(payload)
+}
+
+// Shorten data: URLs so issue messages stay readable.
+func sourceMapDisplay(mapURL string) string {
+	if strings.HasPrefix(mapURL, "data:") {
+		return "inline data: URL"
+	}
+	return mapURL
+}
@@ -0,0 +1,88 @@
+package htmltest
+
+import (
+	"testing"
+)
+
+func TestSourceMapsDisabledByDefault(t *testing.T) {
+	// source maps are not inspected unless asked
+	hT := tTestFile("fixtures/sourcemaps/missing.html")
+	tExpectIssueCount(t, hT, 0)
+}
+
+func TestSourceMapsValid(t *testing.T) {
+	// passes for JS and CSS with existing, valid and inline maps
+	hT := tTestFileOpts("fixtures/sourcemaps/valid.html",
+		map[string]interface{}{"CheckSourceMaps": true})
+	tExpectIssueCount(t, hT, 0)
+}
+
+func TestSourceMapsMissing(t *testing.T) {
+	// fails for a sourceMappingURL to a file that doesn't exist
+	hT := tTestFileOpts("fixtures/sourcemaps/missing.html",
+		map[string]interface{}{"CheckSourceMaps": true})
+	tExpectIssueCount(t, hT, 1)
+	tExpectIssue(t, hT, "source map does not exist: /js/missing.js.map", 1)
+}
+
+func TestSourceMapsInvalidJSON(t *testing.T) {
+	// fails for a map which isn't valid JSON
+	hT := tTestFileOpts("fixtures/sourcemaps/invalid.html",
+		map[string]interface{}{"CheckSourceMaps": true})
+	tExpectIssueCount(t, hT, 1)
+	tExpectIssue(t, hT, "source map is not valid JSON", 1)
+}
+
+func TestSourceMapsOutsideSite(t *testing.T) {
+	// fails for a map above the site root
+	hT := tTestFileOpts("fixtures/sourcemaps/outside.html",
+		map[string]interface{}{"CheckSourceMaps": true})
+	tExpectIssueCount(t, hT, 1)
+	tExpectIssue(t, hT, "source map is outside the site: ../../outside.js.map", 1)
+}
+
+func TestSourceMapsIgnoredDir(t *testing.T) {
+	// passes for an invalid map in an ignored directory, which exists
+	hT := tTestFileOpts("fixtures/sourcemaps/ignored.html",
+		map[string]interface{}{"CheckSourceMaps": true, "IgnoreDirs": []interface{}{"vendor/"}})
+	tExpectIssueCount(t, hT, 0)
+	hT = tTestFileOpts("fixtures/sourcemaps/ignored.html",
+		map[string]interface{}{"CheckSourceMaps": true})
+	tExpectIssueCount(t, hT, 1)
+	tExpectIssue(t, hT, "source map is not valid JSON: /vendor/ignored.js.map", 1)
+}
+
+func TestSourceMapsHeadersFile(t *testing.T) {
+	// fails for a SourceMap entry in _headers pointing nowhere
+	hT := tTestFileOpts("fixtures/sourcemaps/headers.html",
+		map[string]interface{}{"CheckSourceMaps": true})
+	tExpectIssueCount(t, hT, 1)
+	tExpectIssue(t, hT, "source map does not exist: /js/headers-missing.js.map", 1)
+}
+
+func TestSourceMapsForbidReferenced(t *testing.T) {
+	// fails for any referenced map when forbidden
+	hT := tTestFileOpts("fixtures/sourcemaps/valid.html",
+		map[string]interface{}{"ForbidSourceMaps": true})
+	tExpectIssueCount(t, hT, 3)
+	tExpectIssue(t, hT, "source map referenced", 3)
+}
+
+func TestSourceMapsForbidSibling(t *testing.T) {
+	// fails for an unreferenced .map shipped alongside the file when forbidden
+	hT := tTestFileOpts("fixtures/sourcemaps/sibling.html",
+		map[string]interface{}{"ForbidSourceMaps": true})
+	tExpectIssueCount(t, hT, 1)
+	tExpectIssue(t, hT, "source map present: /js/sibling.js.map", 1)
+}
+
+func TestHeadersPathPattern(t *testing.T) {
+	re := headersPathPattern("/assets/*")
+	if !re.MatchString("/assets/js/app.js") || re.MatchString("/other/app.js") {
+		t.Error("splat pattern mismatch")
+	}
+	re = headersPathPattern("/:lang/app.js")
+	if !re.MatchString("/en/app.js") || re.MatchString("/en/gb/app.js") {
+		t.Error("placeholder pattern mismatch")
+	}
+}
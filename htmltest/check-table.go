@@ -0,0 +1,234 @@
+package htmltest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/wjdp/htmltest/htmldoc"
+	"github.com/wjdp/htmltest/issues"
+	"golang.org/x/net/html"
+)
+
+// Values th scope may take
+var tableScopes = map[string]bool{
+	"row":      true,
+	"col":      true,
+	"rowgroup": true,
+	"colgroup": true,
+}
+
+// tableHeader : a th and whether it heads a row, rather than a column
+type tableHeader struct {
+	node      *html.Node
+	rowHeader bool
+}
+
+// tableRowGroup : the rows of a thead, tbody or tfoot, or a lone tr
+type tableRowGroup struct {
+	rows []*html.Node
+	head bool // Is a thead, its headers head columns
+}
+
+// Accessibility checks on every table in the document. Tables are named by
+// id, or their position in the document, in messages.
+func (hT *HTMLTest) checkTables(document *htmldoc.Document) {
+	count := 0
+	document.Walk(func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "table" {
+			count++
+			name := strconv.Itoa(count)
+			if id := htmldoc.GetID(n.Attr); id != "" {
+				name = "#" + id
+			}
+			hT.checkTable(document, n, "table "+name)
+		}
+	})
+}
+
+// Check a single table. Layout tables mustn't use header cells or captions,
+// data tables need header cells with unambiguous scope, headers attributes
+// pointing at them and valid spans.
+func (hT *HTMLTest) checkTable(document *htmldoc.Document, table *html.Node, name string) {
+	addIssue := func(level int, message string) {
+		hT.issueStore.AddIssue(issues.Issue{
+			Level:    level,
+			Document: document,
+			Message:  message,
+			Check:    "Tables",
+		})
+	}
+
+	groups, captions := tableRowGroups(table)
+	cells := make([]*html.Node, 0)
+	for _, group := range groups {
+		for _, tr := range group.rows {
+			cells = append(cells, tableRowCells(tr)...)
+		}
+	}
+
+	role := strings.ToLower(htmldoc.GetAttr(table.Attr, "role"))
+	if relContains(role, "presentation") || relContains(role, "none") {
+		for _, cell := range cells {
+			if cell.Data == "th" {
+				addIssue(issues.LevelError, fmt.Sprintf("layout %s contains <th>, use <td>", name))
+				break
+			}
+		}
+		if captions > 0 {
+			addIssue(issues.LevelError, fmt.Sprintf("layout %s contains <caption>", name))
+		}
+		return
+	}
+	if len(cells) == 0 {
+		return
+	}
+
+	// Lay the cells out on a grid to check spans. Outside a thead, a th with
+	// a td after it in its row heads that row, others head columns.
+	headers := make([]tableHeader, 0)
+	for _, group := range groups {
+		rows := group.rows
+		occupied := make(map[[2]int]bool)
+		for r, tr := range rows {
+			col := 0
+			rowCells := tableRowCells(tr)
+			for c, cell := range rowCells {
+				for occupied[[2]int{r, col}] {
+					col++
+				}
+				colspan := tableSpan(cell, "colspan", 1, 1000, name, addIssue)
+				rowspan := tableSpan(cell, "rowspan", 0, 65534, name, addIssue)
+				// Zero spans the rest of the row group
+				if rowspan == 0 {
+					rowspan = len(rows) - r
+				} else if r+rowspan > len(rows) {
+					addIssue(issues.LevelError,
+						fmt.Sprintf("rowspan %d in %s extends past the last row of its group", rowspan, name))
+					rowspan = len(rows) - r
+				}
+				for i := 0; i < rowspan; i++ {
+					for j := 0; j < colspan; j++ {
+						occupied[[2]int{r + i, col + j}] = true
+					}
+				}
+				if cell.Data == "th" {
+					headers = append(headers, tableHeader{node: cell, rowHeader: !group.head && hasTableData(rowCells[c+1:])})
+				}
+				col += colspan
+			}
+		}
+	}
+
+	if len(headers) == 0 {
+		addIssue(issues.LevelError, fmt.Sprintf("data %s has no header cells", name))
+		return
+	}
+
+	headerIDs := make(map[string]bool)
+	rowHeaders, colHeaders := false, false
+	for _, th := range headers {
+		if id := htmldoc.GetID(th.node.Attr); id != "" {
+			headerIDs[id] = true
+		}
+		rowHeaders = rowHeaders || th.rowHeader
+		colHeaders = colHeaders || !th.rowHeader
+		if htmldoc.AttrPresent(th.node.Attr, "scope") {
+			if scope := htmldoc.GetAttr(th.node.Attr, "scope"); !tableScopes[strings.ToLower(scope)] {
+				addIssue(issues.LevelError, fmt.Sprintf("invalid scope %q in %s", scope, name))
+			}
+		}
+	}
+
+	usesHeaders := false
+	for _, cell := range cells {
+		if !htmldoc.AttrPresent(cell.Attr, "headers") {
+			continue
+		}
+		usesHeaders = true
+		for _, id := range strings.Fields(htmldoc.GetAttr(cell.Attr, "headers")) {
+			if !headerIDs[id] {
+				addIssue(issues.LevelError, fmt.Sprintf("headers references %q, not a <th> id in %s", id, name))
+			}
+		}
+	}
+
+	// With headers across the top and down the side, a th alone could head
+	// either. Tables associating cells by headers attributes don't need scope.
+	if rowHeaders && colHeaders && !usesHeaders {
+		missing := 0
+		for _, th := range headers {
+			if !htmldoc.AttrPresent(th.node.Attr, "scope") {
+				missing++
+			}
+		}
+		if missing > 0 {
+			addIssue(issues.LevelWarning,
+				fmt.Sprintf("%d <th> without scope in %s, which has row and column headers", missing, name))
+		}
+	}
+}
+
+// Read a colspan or rowspan, reporting values which aren't integers within
+// lo and hi. Missing or invalid spans are 1.
+func tableSpan(cell *html.Node, attr string, lo int, hi int, name string,
+	addIssue func(int, string)) int {
+	if !htmldoc.AttrPresent(cell.Attr, attr) {
+		return 1
+	}
+	value := htmldoc.GetAttr(cell.Attr, attr)
+	span, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || span < lo || span > hi {
+		addIssue(issues.LevelError, fmt.Sprintf("invalid %s %q in %s", attr, value, name))
+		return 1
+	}
+	return span
+}
+
+// The rows of a table by row group, thead, tbody or tfoot, and how many
+// captions it has. Rows of nested tables aren't included.
+func tableRowGroups(table *html.Node) ([]tableRowGroup, int) {
+	groups := make([]tableRowGroup, 0)
+	captions := 0
+	for c := table.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode {
+			continue
+		}
+		switch c.Data {
+		case "caption":
+			captions++
+		case "thead", "tbody", "tfoot":
+			rows := make([]*html.Node, 0)
+			for tr := c.FirstChild; tr != nil; tr = tr.NextSibling {
+				if tr.Type == html.ElementNode && tr.Data == "tr" {
+					rows = append(rows, tr)
+				}
+			}
+			groups = append(groups, tableRowGroup{rows: rows, head: c.Data == "thead"})
+		case "tr":
+			groups = append(groups, tableRowGroup{rows: []*html.Node{c}})
+		}
+	}
+	return groups, captions
+}
+
+// The th and td cells of a row.
+func tableRowCells(tr *html.Node) []*html.Node {
+	cells := make([]*html.Node, 0)
+	for c := tr.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && (c.Data == "th" || c.Data == "td") {
+			cells = append(cells, c)
+		}
+	}
+	return cells
+}
+
+// Are any of cells data cells?
+func hasTableData(cells []*html.Node) bool {
+	for _, cell := range cells {
+		if cell.Data == "td" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package htmltest
+
+import (
+	"testing"
+)
+
+func TestTablesDisabled(t *testing.T) {
+	hT := tTestFile("fixtures/tables/bad.html")
+	tExpectIssueCount(t, hT, 0)
+}
+
+func TestTablesGood(t *testing.T) {
+	hT := tTestFileOpts("fixtures/tables/good.html", map[string]interface{}{"CheckTables": true})
+	tExpectIssueCount(t, hT, 0)
+	tExpectIssue(t, hT, "table", 0)
+}
+
+func TestTablesHeaderCells(t *testing.T) {
+	hT := tTestFileOpts("fixtures/tables/bad.html", map[string]interface{}{"CheckTables": true})
+	tExpectIssue(t, hT, "data table #prices has no header cells", 1)
+}
+
+func TestTablesScope(t *testing.T) {
+	hT := tTestFileOpts("fixtures/tables/bad.html", map[string]interface{}{"CheckTables": true})
+	tExpectIssue(t, hT, "invalid scope \"column\" in table 2", 1)
+	tExpectIssue(t, hT, "2 <th> without scope in table 2, which has row and column headers", 1)
+}
+
+func TestTablesHeadersAttribute(t *testing.T) {
+	hT := tTestFileOpts("fixtures/tables/bad.html", map[string]interface{}{"CheckTables": true})
+	tExpectIssue(t, hT, "headers references \"price\", not a <th> id in table 3", 1)
+	tExpectIssue(t, hT, "headers references \"missing\", not a <th> id in table 3", 1)
+	tExpectIssue(t, hT, "headers references \"name\"", 0)
+}
+
+func TestTablesSpans(t *testing.T) {
+	hT := tTestFileOpts("fixtures/tables/bad.html", map[string]interface{}{"CheckTables": true})
+	tExpectIssue(t, hT, "invalid colspan \"0\" in table 4", 1)
+	tExpectIssue(t, hT, "invalid rowspan \"x\" in table 4", 1)
+	tExpectIssue(t, hT, "rowspan 3 in table 4 extends past the last row of its group", 1)
+}
+
+func TestTablesLayout(t *testing.T) {
+	hT := tTestFileOpts("fixtures/tables/bad.html", map[string]interface{}{"CheckTables": true})
+	tExpectIssue(t, hT, "layout table 5 contains <th>, use <td>", 1)
+	tExpectIssue(t, hT, "layout table 5 contains <caption>", 1)
+	tExpectIssueCount(t, hT, 9)
+}
@@ -0,0 +1,142 @@
+package htmltest
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/wjdp/htmltest/htmldoc"
+	"github.com/wjdp/htmltest/issues"
+	"golang.org/x/net/html"
+)
+
+// languageRoot : a language and the directory its pages live in, without
+// leading or trailing slashes
+type languageRoot struct {
+	lang string
+	root string
+}
+
+// Language roots from Languages (root named after the language) and
+// LanguageRoots (explicit roots), sorted by language. Built once.
+func (hT *HTMLTest) languageRoots() []languageRoot {
+	hT.languageRootsOnce.Do(func() {
+		roots := make(map[string]string)
+		for _, item := range hT.opts.Languages {
+			lang := fmt.Sprint(item)
+			roots[lang] = lang
+		}
+		for lang, root := range hT.opts.LanguageRoots {
+			roots[fmt.Sprint(lang)] = strings.Trim(fmt.Sprint(root), "/")
+		}
+		for lang, root := range roots {
+			if root == "" {
+				continue
+			}
+			hT.languageRootsList = append(hT.languageRootsList, languageRoot{lang, root})
+		}
+		sort.Slice(hT.languageRootsList, func(i, j int) bool {
+			return hT.languageRootsList[i].lang < hT.languageRootsList[j].lang
+		})
+	})
+	return hT.languageRootsList
+}
+
+// Language root sitePath is within and sitePath relative to that root.
+func (hT *HTMLTest) languageOf(sitePath string) (languageRoot, string, bool) {
+	sitePath = strings.TrimPrefix(path.Clean("/"+sitePath), "/")
+	for _, lr := range hT.languageRoots() {
+		if sitePath == lr.root {
+			return lr, "", true
+		}
+		if strings.HasPrefix(sitePath, lr.root+"/") {
+			return lr, strings.TrimPrefix(sitePath, lr.root+"/"), true
+		}
+	}
+	return languageRoot{}, "", false
+}
+
+// Checks an internal link doesn't cross from one language's pages into
+// another's. Links marked with hreflang or lang, rel=alternate links and
+// links to the same page in another language are taken to be language
+// switchers.
+func (hT *HTMLTest) checkLanguageLink(ref *htmldoc.Reference, node *html.Node) {
+	if len(hT.languageRoots()) == 0 {
+		return
+	}
+	from, fromRel, ok := hT.languageOf(ref.Document.SitePath)
+	if !ok {
+		return
+	}
+	to, toRel, ok := hT.languageOf(ref.RefSitePath())
+	if !ok || to.lang == from.lang {
+		return
+	}
+
+	attrs := htmldoc.ExtractAttrs(node.Attr, []string{"rel"})
+	if htmldoc.AttrPresent(node.Attr, "hreflang") || htmldoc.AttrPresent(node.Attr, "lang") ||
+		relContains(attrs["rel"], "alternate") {
+		return
+	}
+	// Linking to the equivalent page, directory links may omit the index
+	if toRel == fromRel || path.Join(toRel, hT.opts.DirectoryIndex) == fromRel {
+		return
+	}
+
+	hT.issueStore.AddIssue(issues.Issue{
+		Level:     issues.LevelError,
+		Message:   fmt.Sprintf("links from %s pages into %s, mark language switchers with hreflang", from.lang, to.lang),
+		Reference: ref,
+		Check:     "Translations",
+	})
+}
+
+// Site level check, run once all documents have been tested. Reports pages
+// present under some language roots but missing from others, once per page.
+func (hT *HTMLTest) checkTranslations() {
+	roots := hT.languageRoots()
+	if len(roots) < 2 {
+		return
+	}
+
+	// Languages each page, relative to its root, exists in
+	pages := make(map[string]map[string]bool)
+	for _, document := range hT.documentStore.Documents {
+		lr, rel, ok := hT.languageOf(document.SitePath)
+		if !ok || hT.opts.isTranslationIgnored(rel) {
+			continue
+		}
+		if _, ok := pages[rel]; !ok {
+			pages[rel] = make(map[string]bool)
+		}
+		pages[rel][lr.lang] = true
+	}
+
+	rels := make([]string, 0, len(pages))
+	for rel := range pages {
+		rels = append(rels, rel)
+	}
+	sort.Strings(rels)
+
+	for _, rel := range rels {
+		present, missing := make([]string, 0), make([]string, 0)
+		for _, lr := range roots {
+			if pages[rel][lr.lang] {
+				present = append(present, lr.lang)
+			} else {
+				missing = append(missing, lr.lang)
+			}
+		}
+		if len(missing) == 0 {
+			continue
+		}
+		// Spans several documents so isn't attributed to any one of them
+		hT.issueStore.AddIssue(issues.Issue{
+			Level: issues.LevelWarning,
+			Message: fmt.Sprintf("translation missing in %s: %s (present in %s)",
+				strings.Join(missing, ", "), rel, strings.Join(present, ", ")),
+			Check: "Translations",
+		})
+	}
+}
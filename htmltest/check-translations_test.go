@@ -0,0 +1,51 @@
+package htmltest
+
+import (
+	"testing"
+)
+
+func TestTranslationsDisabledByDefault(t *testing.T) {
+	// nothing is compared without languages
+	hT := tTestDirectory("fixtures/translations")
+	tExpectIssueCount(t, hT, 0)
+	tExpectIssue(t, hT, "translation missing", 0)
+}
+
+func TestTranslationsMissing(t *testing.T) {
+	// reports each page missing from some languages once
+	hT := tTestDirectoryOpts("fixtures/translations",
+		map[string]interface{}{"Languages": []interface{}{"en", "de", "fr"}})
+	tExpectIssue(t, hT, "translation missing", 2)
+	tExpectIssue(t, hT, "translation missing in de, fr: blog.html (present in en)", 1)
+	tExpectIssue(t, hT, "translation missing in fr: about/index.html (present in de, en)", 1)
+}
+
+func TestTranslationsCrossLinks(t *testing.T) {
+	// links into another language must be switchers
+	hT := tTestDirectoryOpts("fixtures/translations",
+		map[string]interface{}{"Languages": []interface{}{"en", "de", "fr"}})
+	tExpectIssueCount(t, hT, 1)
+	tExpectIssue(t, hT, "links from en pages into de, mark language switchers with hreflang", 1)
+}
+
+func TestTranslationsIgnore(t *testing.T) {
+	// pages matching IgnoreTranslations aren't compared
+	hT := tTestDirectoryOpts("fixtures/translations",
+		map[string]interface{}{
+			"Languages":          []interface{}{"en", "de", "fr"},
+			"IgnoreTranslations": []interface{}{"^blog"},
+		})
+	tExpectIssue(t, hT, "translation missing", 1)
+}
+
+func TestTranslationsLanguageRoots(t *testing.T) {
+	// roots can be mapped explicitly
+	hT := tTestDirectoryOpts("fixtures/translations",
+		map[string]interface{}{"LanguageRoots": map[interface{}]interface{}{
+			"en-GB": "/en/",
+			"de-DE": "de",
+		}})
+	tExpectIssue(t, hT, "translation missing", 1)
+	tExpectIssue(t, hT, "translation missing in de-DE: blog.html (present in en-GB)", 1)
+	tExpectIssue(t, hT, "links from en-GB pages into de-DE", 1)
+}
@@ -0,0 +1,99 @@
+package htmltest
+
+import (
+	"io/ioutil"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/wjdp/htmltest/issues"
+)
+
+// Matches @import rules and url() values in CSS, capturing the URL
+var cssURLRegexp = regexp.MustCompile(`(?:@import\s+(?:url\(\s*)?|url\(\s*)["']?([^"'\s);]+)`)
+
+// Record that an internal reference resolved to the file at sitePath. Thread
+// safe.
+func (hT *HTMLTest) markReferenced(sitePath string) {
+	if !hT.opts.CheckUnreferencedFiles {
+		return
+	}
+	hT.referencedMutex.Lock()
+	if hT.referenced == nil {
+		hT.referenced = make(map[string]bool)
+	}
+	hT.referenced[path.Clean("/"+sitePath)] = true
+	hT.referencedMutex.Unlock()
+}
+
+// Site level check, run once all documents have been tested. Reports files in
+// the asset index which no internal reference resolved to. Documents are only
+// reported if they aren't a directory index, those are reachable by URL alone.
+// Stylesheets referenced count their @imports and url()s as references.
+func (hT *HTMLTest) checkUnreferenced() {
+	hT.referencedMutex.Lock()
+	defer hT.referencedMutex.Unlock()
+	if hT.referenced == nil {
+		hT.referenced = make(map[string]bool)
+	}
+	hT.markStylesheetReferences()
+	for _, sitePath := range hT.documentStore.AssetFiles() {
+		if path.Base(sitePath) == hT.opts.DirectoryIndex || hT.referenced["/"+sitePath] ||
+			hT.opts.isUnreferencedIgnored("/"+sitePath) {
+			continue
+		}
+		hT.issueStore.AddIssue(issues.Issue{
+			Level:   issues.LevelWarning,
+			Message: "file is not referenced: /" + sitePath,
+			Check:   "Unreferenced files",
+		})
+	}
+}
+
+// Mark the local files referenced stylesheets @import or use in url(),
+// following them through imported stylesheets. Expects referencedMutex held.
+func (hT *HTMLTest) markStylesheetReferences() {
+	queue := make([]string, 0)
+	for sitePath := range hT.referenced {
+		if path.Ext(sitePath) == ".css" {
+			queue = append(queue, sitePath)
+		}
+	}
+	sort.Strings(queue)
+	for len(queue) > 0 {
+		sitePath := queue[0]
+		queue = queue[1:]
+		b, err := ioutil.ReadFile(path.Join(hT.opts.DirectoryPath, sitePath))
+		if err != nil {
+			continue
+		}
+		for _, ref := range cssLocalURLs(string(b), path.Dir(sitePath)) {
+			if hT.referenced[ref] {
+				continue
+			}
+			hT.referenced[ref] = true
+			if path.Ext(ref) == ".css" {
+				queue = append(queue, ref)
+			}
+		}
+	}
+}
+
+// Site paths of the local files a stylesheet references, resolved against
+// basePath. Comments, data: and fragment only URLs are skipped.
+func cssLocalURLs(css string, basePath string) []string {
+	refs := make([]string, 0)
+	for _, m := range cssURLRegexp.FindAllStringSubmatch(cssCommentRegexp.ReplaceAllString(css, ""), -1) {
+		u, err := url.Parse(m[1])
+		if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" {
+			continue
+		}
+		if !strings.HasPrefix(u.Path, "/") {
+			u.Path = path.Join(basePath, u.Path)
+		}
+		refs = append(refs, path.Clean("/"+u.Path))
+	}
+	return refs
+}
@@ -0,0 +1,54 @@
+package htmltest
+
+import (
+	"testing"
+
+	"github.com/daviddengcn/go-assert"
+)
+
+func TestUnreferencedDefault(t *testing.T) {
+	// doesn't look for unreferenced files by default
+	hT := tTestDirectory("fixtures/unreferenced")
+	tExpectIssueCount(t, hT, 0)
+	tExpectIssue(t, hT, "file is not referenced", 0)
+}
+
+func TestUnreferencedFiles(t *testing.T) {
+	// warns about files nothing links to, directory indexes are exempt
+	hT := tTestDirectoryOpts("fixtures/unreferenced",
+		map[string]interface{}{"CheckUnreferencedFiles": true})
+	tExpectIssueCount(t, hT, 0)
+	tExpectIssue(t, hT, "file is not referenced", 3)
+	tExpectIssue(t, hT, "file is not referenced: /img/unused.png", 1)
+	tExpectIssue(t, hT, "file is not referenced: /about/orphan.html", 1)
+	tExpectIssue(t, hT, "file is not referenced: /robots.txt", 1)
+}
+
+func TestCSSLocalURLs(t *testing.T) {
+	// @imports and url()s resolve against the stylesheet, others are skipped
+	refs := cssLocalURLs(`@import url("a.css"); @import 'b.css' screen;
+		/* url(commented.png) */
+		.x { background: url(../img/x.png), url(data:image/png;base64,AA); }
+		.y { filter: url(#blur); mask: url(https://example.com/m.svg); }
+		@font-face { src: url(/fonts/f.woff2?v=1#iefix) }`, "/css")
+	assert.StringEquals(t, "refs", refs, []string{"/css/a.css", "/css/b.css", "/img/x.png", "/fonts/f.woff2"})
+}
+
+func TestUnreferencedFilesIgnore(t *testing.T) {
+	// files matching IgnoreUnreferenced and within IgnoreDirs aren't reported
+	hT := tTestDirectoryOpts("fixtures/unreferenced",
+		map[string]interface{}{
+			"CheckUnreferencedFiles": true,
+			"IgnoreUnreferenced":     []interface{}{"^/robots\\.txt$"},
+			"IgnoreDirs":             []interface{}{"^img/"},
+		})
+	tExpectIssue(t, hT, "file is not referenced", 1)
+	tExpectIssue(t, hT, "file is not referenced: /about/orphan.html", 1)
+}
+
+func TestUnreferencedSingleFile(t *testing.T) {
+	// only runs on whole directories
+	hT := tTestFileOpts("fixtures/unreferenced/index.html",
+		map[string]interface{}{"CheckUnreferencedFiles": true})
+	tExpectIssue(t, hT, "file is not referenced", 0)
+}
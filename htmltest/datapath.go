@@ -0,0 +1,177 @@
+package htmltest
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// dataPathStep : one step of a parsed data path selector
+type dataPathStep struct {
+	key       string // Object key to descend into, when not wildcard or index
+	index     int    // Array index to descend into, when isIndex
+	isIndex   bool
+	wildcard  bool // Every member of an object or array
+	recursive bool // Apply at any depth, from ..
+}
+
+// dataPathMatch : a value selected from a data file and where it was found
+type dataPathMatch struct {
+	value interface{}
+	field string
+}
+
+// Matches a key which needs no quoting in a rendered field path
+var dataPathIdentRegexp = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$-]*$`)
+
+// Parse a JSONPath-like selector. Supported: $ root, .key, ['key'], [n], [*],
+// .* and .. for any depth, e.g. $..links[*].href
+func parseDataPath(selector string) ([]dataPathStep, error) {
+	s := strings.TrimSpace(selector)
+	if !strings.HasPrefix(s, "$") {
+		s = "$." + s
+	}
+	s = s[1:]
+	steps := make([]dataPathStep, 0)
+	for len(s) > 0 {
+		recursive := false
+		switch {
+		case strings.HasPrefix(s, ".."):
+			recursive = true
+			s = s[2:]
+		case s[0] == '.':
+			s = s[1:]
+		}
+		if len(s) == 0 {
+			return nil, fmt.Errorf("selector %q ends unexpectedly", selector)
+		}
+
+		step := dataPathStep{recursive: recursive}
+		if s[0] == '[' {
+			end := strings.Index(s, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("selector %q has an unclosed [", selector)
+			}
+			inner := strings.TrimSpace(s[1:end])
+			s = s[end+1:]
+			switch {
+			case inner == "*":
+				step.wildcard = true
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				step.key = inner[1 : len(inner)-1]
+			default:
+				i, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("selector %q has an invalid index [%s]", selector, inner)
+				}
+				step.index, step.isIndex = i, true
+			}
+		} else {
+			end := strings.IndexAny(s, ".[")
+			if end < 0 {
+				end = len(s)
+			}
+			step.key = s[:end]
+			s = s[end:]
+			if step.key == "*" {
+				step.wildcard, step.key = true, ""
+			}
+			if step.key == "" && !step.wildcard {
+				return nil, fmt.Errorf("selector %q has an empty key", selector)
+			}
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// Select values from data, as decoded from JSON or YAML, following steps.
+// Matches are in document order, object keys sorted.
+func selectDataPath(data interface{}, steps []dataPathStep) []dataPathMatch {
+	matches := []dataPathMatch{{value: data, field: "$"}}
+	for _, step := range steps {
+		next := make([]dataPathMatch, 0)
+		for _, m := range matches {
+			if step.recursive {
+				for _, d := range dataDescendants(m) {
+					next = append(next, dataStep(d, step)...)
+				}
+			} else {
+				next = append(next, dataStep(m, step)...)
+			}
+		}
+		matches = next
+	}
+	return matches
+}
+
+// Apply a single non-recursive step to m.
+func dataStep(m dataPathMatch, step dataPathStep) []dataPathMatch {
+	out := make([]dataPathMatch, 0)
+	switch v := m.value.(type) {
+	case map[string]interface{}:
+		if step.isIndex {
+			break
+		}
+		for _, key := range dataSortedKeys(v) {
+			if step.wildcard || key == step.key {
+				out = append(out, dataPathMatch{value: v[key], field: dataFieldKey(m.field, key)})
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			if step.wildcard || (step.isIndex && i == step.index) {
+				out = append(out, dataPathMatch{value: item, field: fmt.Sprintf("%s[%d]", m.field, i)})
+			}
+		}
+	}
+	return out
+}
+
+// m and every value nested within it, depth first.
+func dataDescendants(m dataPathMatch) []dataPathMatch {
+	out := []dataPathMatch{m}
+	for _, child := range dataStep(m, dataPathStep{wildcard: true}) {
+		out = append(out, dataDescendants(child)...)
+	}
+	return out
+}
+
+// Render a field path step into key.
+func dataFieldKey(field string, key string) string {
+	if dataPathIdentRegexp.MatchString(key) {
+		return field + "." + key
+	}
+	return field + "[" + strconv.Quote(key) + "]"
+}
+
+// Keys of an object, sorted so output is stable.
+func dataSortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Convert YAML's map[interface{}]interface{} to the map[string]interface{}
+// JSON decodes to, recursively.
+func normaliseData(data interface{}) interface{} {
+	switch v := data.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			m[fmt.Sprint(key)] = normaliseData(value)
+		}
+		return m
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normaliseData(item)
+		}
+		return v
+	}
+	return data
+}
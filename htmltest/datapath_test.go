@@ -0,0 +1,56 @@
+package htmltest
+
+import (
+	"testing"
+
+	"github.com/daviddengcn/go-assert"
+)
+
+// Fields selected by selector from data
+func tDataPathFields(t *testing.T, data interface{}, selector string) []string {
+	steps, err := parseDataPath(selector)
+	assert.Equals(t, selector+" error", err, nil)
+	fields := make([]string, 0)
+	for _, m := range selectDataPath(data, steps) {
+		fields = append(fields, m.field)
+	}
+	return fields
+}
+
+func TestDataPathSelect(t *testing.T) {
+	data := map[string]interface{}{
+		"items": []interface{}{
+			map[string]interface{}{"url": "/a", "more": map[string]interface{}{"url": "/b"}},
+			map[string]interface{}{"url": "/c"},
+		},
+		"odd key": "/d",
+	}
+	assert.StringEquals(t, "key", tDataPathFields(t, data, "$.items[1].url"), []string{"$.items[1].url"})
+	assert.StringEquals(t, "no root", tDataPathFields(t, data, "items[0].url"), []string{"$.items[0].url"})
+	assert.StringEquals(t, "wildcard", tDataPathFields(t, data, "$.items[*].url"),
+		[]string{"$.items[0].url", "$.items[1].url"})
+	assert.StringEquals(t, "dot wildcard", tDataPathFields(t, data, "$.*"),
+		[]string{"$.items", "$[\"odd key\"]"})
+	assert.StringEquals(t, "recursive", tDataPathFields(t, data, "$..url"),
+		[]string{"$.items[0].url", "$.items[0].more.url", "$.items[1].url"})
+	assert.StringEquals(t, "quoted", tDataPathFields(t, data, "$['odd key']"), []string{"$[\"odd key\"]"})
+	assert.StringEquals(t, "missing", tDataPathFields(t, data, "$.items[5].url"), []string{})
+}
+
+func TestDataPathParseErrors(t *testing.T) {
+	for _, selector := range []string{"$.items[", "$.items[x]", "$.", "$..", "$.a..[0"} {
+		_, err := parseDataPath(selector)
+		assert.NotEquals(t, selector, err, nil)
+	}
+}
+
+func TestNormaliseData(t *testing.T) {
+	data := normaliseData(map[interface{}]interface{}{
+		"a": []interface{}{map[interface{}]interface{}{1: "x"}},
+	})
+	m, ok := data.(map[string]interface{})
+	assert.Equals(t, "map", ok, true)
+	inner, ok := m["a"].([]interface{})[0].(map[string]interface{})
+	assert.Equals(t, "inner map", ok, true)
+	assert.Equals(t, "inner key", inner["1"], "x")
+}
@@ -0,0 +1,201 @@
+package htmltest
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wjdp/htmltest/htmldoc"
+	"github.com/wjdp/htmltest/issues"
+	"github.com/wjdp/htmltest/refcache"
+)
+
+// failureSet : documents and external URLs with errors, thread safe
+type failureSet struct {
+	documents map[string]bool // By site path
+	urls      map[string]bool // As passed to checkExternal
+	mutex     sync.Mutex
+}
+
+func newFailureSet() *failureSet {
+	return &failureSet{
+		documents: make(map[string]bool),
+		urls:      make(map[string]bool),
+	}
+}
+
+func (fS *failureSet) add(sitePath string, urlStr string) {
+	fS.mutex.Lock()
+	defer fS.mutex.Unlock()
+	if sitePath != "" {
+		fS.documents[sitePath] = true
+	}
+	if urlStr != "" {
+		fS.urls[urlStr] = true
+	}
+}
+
+func (fS *failureSet) hasDocument(sitePath string) bool {
+	fS.mutex.Lock()
+	defer fS.mutex.Unlock()
+	return fS.documents[sitePath]
+}
+
+func (fS *failureSet) hasURL(urlStr string) bool {
+	fS.mutex.Lock()
+	defer fS.mutex.Unlock()
+	return fS.urls[urlStr]
+}
+
+// Sorted documents and URLs in fS which aren't in other.
+func (fS *failureSet) without(other *failureSet) ([]string, []string) {
+	fS.mutex.Lock()
+	defer fS.mutex.Unlock()
+	documents, urls := make([]string, 0), make([]string, 0)
+	for sitePath := range fS.documents {
+		if !other.hasDocument(sitePath) {
+			documents = append(documents, sitePath)
+		}
+	}
+	for urlStr := range fS.urls {
+		if !other.hasURL(urlStr) {
+			urls = append(urls, urlStr)
+		}
+	}
+	sort.Strings(documents)
+	sort.Strings(urls)
+	return documents, urls
+}
+
+// The external URL checkExternal checks for urlStr, after query stripping.
+func (hT *HTMLTest) externalURL(urlStr string) string {
+	if strings.HasPrefix(urlStr, "//") {
+		urlStr = "https:" + urlStr
+	}
+	if hT.opts.StripQueryString && !InList(hT.opts.StripQueryExcludes, urlStr) {
+		urlStr = htmldoc.URLStripQueryString(urlStr)
+	}
+	return urlStr
+}
+
+// Wrap an issue hook so errors are recorded in hT.failures first.
+func (hT *HTMLTest) recordFailures(next func(issues.Issue)) func(issues.Issue) {
+	return func(issue issues.Issue) {
+		if issue.Level == issues.LevelError {
+			sitePath, urlStr := "", ""
+			if issue.Document != nil {
+				sitePath = issue.Document.SitePath
+			}
+			if issue.Reference != nil {
+				if issue.Reference.Document != nil {
+					sitePath = issue.Reference.Document.SitePath
+				}
+				if scheme := issue.Reference.Scheme(); scheme == "http" || scheme == "https" {
+					urlStr = hT.externalURL(issue.Reference.URLString())
+				}
+			}
+			hT.failures.add(sitePath, urlStr)
+		}
+		if next != nil {
+			next(issue)
+		}
+	}
+}
+
+// Save this run's failures to the refcache for OnlyFailed to pick up.
+func (hT *HTMLTest) saveFailures() {
+	documents, urls := hT.failures.without(newFailureSet())
+	hT.refCache.SaveFailures(refcache.RunFailures{
+		Documents: documents,
+		URLs:      urls,
+		Finished:  time.Now(),
+	})
+}
+
+// Load the failures of the previous run for OnlyFailed, preferring a JSON
+// report written by one of sinks and falling back to the refcache.
+func (hT *HTMLTest) loadPreviousFailures(sinks []issues.Sink) error {
+	hT.previousFailures = newFailureSet()
+	for _, sink := range sinks {
+		if sink.Format != issues.FormatJSON {
+			continue
+		}
+		b, err := ioutil.ReadFile(sink.Path)
+		if err != nil {
+			continue
+		}
+		var report struct {
+			Issues []struct {
+				Level     string `json:"level"`
+				Document  string `json:"document"`
+				Reference string `json:"reference"`
+			} `json:"issues"`
+		}
+		if json.Unmarshal(b, &report) != nil {
+			continue
+		}
+		for _, issue := range report.Issues {
+			if issue.Level != issues.LevelName(issues.LevelError) {
+				continue
+			}
+			// Drop the field of data file references
+			reference := strings.SplitN(issue.Reference, " at $", 2)[0]
+			urlStr := ""
+			if strings.HasPrefix(reference, "http://") || strings.HasPrefix(reference, "https://") ||
+				strings.HasPrefix(reference, "//") {
+				urlStr = hT.externalURL(reference)
+			}
+			hT.previousFailures.add(issue.Document, urlStr)
+		}
+		return nil
+	}
+
+	failures, ok := hT.refCache.GetFailures()
+	if !ok {
+		return errors.New("OnlyFailed needs the results of a previous run with EnableCache or a json OutputSinks report")
+	}
+	for _, sitePath := range failures.Documents {
+		hT.previousFailures.add(sitePath, "")
+	}
+	for _, urlStr := range failures.URLs {
+		hT.previousFailures.add("", urlStr)
+	}
+	return nil
+}
+
+// Under OnlyFailed, whether document should be tested: it had errors last
+// run or references an external URL which did.
+func (hT *HTMLTest) onlyFailedSelected(document *htmldoc.Document) bool {
+	if !hT.opts.OnlyFailed || hT.previousFailures.hasDocument(document.SitePath) {
+		return true
+	}
+	document.Parse()
+	for _, n := range document.NodesOfInterest {
+		for _, attr := range n.Attr {
+			if hT.previousFailures.hasURL(hT.externalURL(strings.TrimSpace(attr.Val))) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Under OnlyFailed, whether an external URL is skipped: it passed last run
+// and its document had no errors.
+func (hT *HTMLTest) onlyFailedSkipsURL(ref *htmldoc.Reference, urlStr string) bool {
+	return hT.opts.OnlyFailed && !hT.previousFailures.hasDocument(ref.Document.SitePath) &&
+		!hT.previousFailures.hasURL(urlStr)
+}
+
+// FixedSinceLastRun : With OnlyFailed, the documents and external URLs which
+// had errors in the previous run and don't any more. Both sorted.
+func (hT *HTMLTest) FixedSinceLastRun() ([]string, []string) {
+	if hT.previousFailures == nil {
+		return []string{}, []string{}
+	}
+	return hT.previousFailures.without(hT.failures)
+}
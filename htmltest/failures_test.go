@@ -0,0 +1,137 @@
+package htmltest
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"sync"
+	"testing"
+
+	"github.com/daviddengcn/go-assert"
+	"github.com/wjdp/htmltest/output"
+)
+
+// A site with a broken external link in a.html, a working one in b.html and
+// a broken internal link in c.html, served by a server where /gone 404s
+// until fixed. Returns the site, a scratch dir, the server, functions to fix
+// the site and clean up, and a count of hits by path.
+func tFailuresSite() (string, string, *httptest.Server, func(), func(), map[string]int) {
+	var mutex sync.Mutex
+	hits := make(map[string]int)
+	fixed := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		hits[r.URL.Path]++
+		if r.URL.Path == "/gone" && !fixed {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	site, err := ioutil.TempDir("", "htmltest-failures")
+	output.CheckErrorPanic(err)
+	scratch, err := ioutil.TempDir("", "htmltest-failures-out")
+	output.CheckErrorPanic(err)
+	pages := map[string]string{
+		"a.html": `<a href="` + server.URL + `/gone">gone</a>`,
+		"b.html": `<a href="` + server.URL + `/ok">ok</a>`,
+		"c.html": `<a href="missing.html">missing</a>`,
+	}
+	for name, body := range pages {
+		err = ioutil.WriteFile(path.Join(site, name), []byte("<html><body>"+body+"</body></html>"), 0644)
+		output.CheckErrorPanic(err)
+	}
+	cleanup := func() {
+		server.Close()
+		os.RemoveAll(site)
+		os.RemoveAll(scratch)
+	}
+
+	fix := func() {
+		mutex.Lock()
+		fixed = true
+		for k := range hits {
+			delete(hits, k)
+		}
+		mutex.Unlock()
+		err := ioutil.WriteFile(path.Join(site, "missing.html"), []byte("<html></html>"), 0644)
+		output.CheckErrorPanic(err)
+	}
+	return site, scratch, server, fix, cleanup, hits
+}
+
+func TestOnlyFailedFromRefCache(t *testing.T) {
+	site, scratch, server, fix, cleanup, hits := tFailuresSite()
+	defer cleanup()
+	opts := map[string]interface{}{
+		"EnableCache":  true,
+		"OutputDir":    scratch,
+		"CacheExpires": "0s", // Passing URLs aren't cached either
+	}
+
+	hT := tTestDirectoryOpts(site, opts)
+	tExpectIssueCount(t, hT, 2)
+
+	fix()
+	opts["OnlyFailed"] = true
+	hT = tTestDirectoryOpts(site, opts)
+	tExpectIssueCount(t, hT, 0)
+	assert.Equals(t, "gone hits", hits["/gone"], 1)
+	assert.Equals(t, "ok hits", hits["/ok"], 0)
+
+	documents, urls := hT.FixedSinceLastRun()
+	assert.StringEquals(t, "fixed documents", documents, []string{"a.html", "c.html"})
+	assert.StringEquals(t, "fixed urls", urls, []string{server.URL + "/gone"})
+}
+
+func TestOnlyFailedFromReport(t *testing.T) {
+	site, scratch, _, fix, cleanup, hits := tFailuresSite()
+	defer cleanup()
+	opts := map[string]interface{}{
+		"OutputDir": scratch,
+		"OutputSinks": []interface{}{
+			map[string]interface{}{"Path": "report.json", "Format": "json", "Level": "error"},
+		},
+	}
+
+	hT := tTestDirectoryOpts(site, opts)
+	tExpectIssueCount(t, hT, 2)
+
+	fix()
+	opts["OnlyFailed"] = true
+	hT = tTestDirectoryOpts(site, opts)
+	tExpectIssueCount(t, hT, 0)
+	assert.Equals(t, "ok hits", hits["/ok"], 0)
+	documents, _ := hT.FixedSinceLastRun()
+	assert.StringEquals(t, "fixed documents", documents, []string{"a.html", "c.html"})
+}
+
+func TestOnlyFailedStillFailing(t *testing.T) {
+	// unfixed failures are reported again and stay recorded
+	site, scratch, _, _, cleanup, _ := tFailuresSite()
+	defer cleanup()
+	opts := map[string]interface{}{"EnableCache": true, "OutputDir": scratch}
+	tTestDirectoryOpts(site, opts)
+
+	opts["OnlyFailed"] = true
+	hT := tTestDirectoryOpts(site, opts)
+	tExpectIssueCount(t, hT, 2)
+	documents, urls := hT.FixedSinceLastRun()
+	assert.Equals(t, "fixed", len(documents)+len(urls), 0)
+
+	hT = tTestDirectoryOpts(site, opts)
+	tExpectIssueCount(t, hT, 2)
+}
+
+func TestOnlyFailedWithoutPreviousRun(t *testing.T) {
+	scratch, err := ioutil.TempDir("", "htmltest-failures-out")
+	output.CheckErrorPanic(err)
+	defer os.RemoveAll(scratch)
+	opts := defaultDirectoryTestOpts("fixtures/links")
+	opts["OnlyFailed"] = true
+	opts["OutputDir"] = scratch
+	_, err = Test(opts)
+	assert.NotEquals(t, "error", err, nil)
+}
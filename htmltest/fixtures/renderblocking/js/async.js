@@ -0,0 +1 @@
+// async
@@ -0,0 +1 @@
+// deferred
@@ -0,0 +1,2 @@
+console.log("ignored");
+//# sourceMappingURL=../vendor/ignored.js.map
@@ -0,0 +1,2 @@
+console.log("inline");
+//# sourceMappingURL=data:application/json;base64,eyJ2ZXJzaW9uIjozLCJtYXBwaW5ncyI6IiJ9
@@ -0,0 +1,2 @@
+console.log("invalid");
+//# sourceMappingURL=/js/invalid.js.map
@@ -0,0 +1,2 @@
+console.log("missing");
+//# sourceMappingURL=missing.js.map
@@ -0,0 +1,2 @@
+console.log("outside");
+//# sourceMappingURL=../../outside.js.map
@@ -0,0 +1,2 @@
+console.log("valid");
+//# sourceMappingURL=valid.js.map
@@ -0,0 +1,60 @@
+package htmltest
+
+import (
+	"time"
+
+	"github.com/wjdp/htmltest/htmldoc"
+	"github.com/wjdp/htmltest/issues"
+)
+
+// Hooks : callbacks for library users wanting to follow a run as it happens,
+// pass to TestWithHooks. Any may be left nil. When TestFilesConcurrently is set
+// callbacks are called from many goroutines at once.
+type Hooks struct {
+	DocumentStarted  func(document *htmldoc.Document)
+	DocumentFinished func(document *htmldoc.Document)
+	IssueAdded       func(issue issues.Issue)
+	ExternalStarted  func(ref *htmldoc.Reference, urlStr string) // Only called when a request is made
+	ExternalFinished func(event ExternalEvent)                   // Called for cached and fresh checks
+	RunFinished      func(hT *HTMLTest)
+}
+
+// ExternalEvent : outcome of checking a single external reference.
+type ExternalEvent struct {
+	Reference  *htmldoc.Reference
+	URL        string    // URL checked, after query stripping
+	CacheHit   bool      // Result came from the refcache, no request was made
+	StatusCode int       // Zero when the request failed
+	Err        error     // Client error, if any
+	Started    time.Time // When the check began
+}
+
+func (h *Hooks) documentStarted(document *htmldoc.Document) {
+	if h.DocumentStarted != nil {
+		h.DocumentStarted(document)
+	}
+}
+
+func (h *Hooks) documentFinished(document *htmldoc.Document) {
+	if h.DocumentFinished != nil {
+		h.DocumentFinished(document)
+	}
+}
+
+func (h *Hooks) externalStarted(ref *htmldoc.Reference, urlStr string) {
+	if h.ExternalStarted != nil {
+		h.ExternalStarted(ref, urlStr)
+	}
+}
+
+func (h *Hooks) externalFinished(event ExternalEvent) {
+	if h.ExternalFinished != nil {
+		h.ExternalFinished(event)
+	}
+}
+
+func (h *Hooks) runFinished(hT *HTMLTest) {
+	if h.RunFinished != nil {
+		h.RunFinished(hT)
+	}
+}
@@ -0,0 +1,65 @@
+package htmltest
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/daviddengcn/go-assert"
+	"github.com/wjdp/htmltest/htmldoc"
+	"github.com/wjdp/htmltest/issues"
+	"github.com/wjdp/htmltest/output"
+)
+
+func TestHooksDocumentsAndIssues(t *testing.T) {
+	var mutex sync.Mutex
+	started, finished, errors, runs := 0, 0, 0, 0
+	hooks := Hooks{
+		DocumentStarted: func(document *htmldoc.Document) {
+			mutex.Lock()
+			started++
+			mutex.Unlock()
+		},
+		DocumentFinished: func(document *htmldoc.Document) {
+			mutex.Lock()
+			finished++
+			mutex.Unlock()
+		},
+		IssueAdded: func(issue issues.Issue) {
+			mutex.Lock()
+			if issue.Level == issues.LevelError {
+				errors++
+			}
+			mutex.Unlock()
+		},
+		RunFinished: func(hT *HTMLTest) {
+			runs++
+		},
+	}
+	hT, err := TestWithHooks(defaultDirectoryTestOpts("fixtures/documents/folder-not-ok"), hooks)
+	output.CheckErrorPanic(err)
+	assert.Equals(t, "documents started", started, hT.CountDocuments())
+	assert.Equals(t, "documents finished", finished, hT.CountDocuments())
+	assert.Equals(t, "errors", errors, hT.CountErrors())
+	assert.Equals(t, "runs finished", runs, 1)
+}
+
+func TestHooksExternal(t *testing.T) {
+	var events []ExternalEvent
+	requests := 0
+	hooks := Hooks{
+		ExternalStarted: func(ref *htmldoc.Reference, urlStr string) {
+			requests++
+		},
+		ExternalFinished: func(event ExternalEvent) {
+			events = append(events, event)
+		},
+	}
+	opts := defaultFileTestOpts("fixtures/links/non_https.html")
+	opts["VCREnable"] = true
+	_, err := TestWithHooks(opts, hooks)
+	output.CheckErrorPanic(err)
+	assert.Equals(t, "requests", requests, 1)
+	assert.Equals(t, "events", len(events), 1)
+	assert.IsFalse(t, "cache hit", events[0].CacheHit)
+	assert.IsTrue(t, "status", statusCodeValid(events[0].StatusCode))
+}
@@ -3,7 +3,6 @@
 package htmltest
 
 import (
-	"crypto/tls"
 	"errors"
 	"fmt"
 	"github.com/wjdp/htmltest/htmldoc"
@@ -27,16 +26,54 @@ const vcrCassetteBasePath string = "fixtures/vcr"
 type HTMLTest struct {
 	opts          Options
 	httpClient    *http.Client
+	httpTransport *http.Transport // Beneath httpClient, for inspection
+	httpStats     *HTTPStats      // Updated by httpClient
 	httpChannel   chan bool
 	documentStore htmldoc.DocumentStore
 	issueStore    issues.IssueStore
 	refCache      *refcache.RefCache
+	hooks         Hooks
+
+	sourceMapCache   map[string]*sourceMapResult // Source map details by site path
+	sourceMapMutex   sync.Mutex                  // Controls access to sourceMapCache
+	headersRules     []headersRule               // SourceMap entries read from _headers
+	headersRulesOnce sync.Once                   // Guards reading headersRules
+
+	secretPatternsCompiled []secretPattern // Built in and user secret patterns
+	secretPatternsOnce     sync.Once       // Guards compiling secretPatternsCompiled
+
+	referenced      map[string]bool // Site paths internal references resolved to
+	referencedMutex sync.Mutex      // Controls access to referenced
+
+	languageRootsList []languageRoot // Built from Languages and LanguageRoots
+	languageRootsOnce sync.Once      // Guards building languageRootsList
+
+	dataFileRules []dataFileRule // Built from DataFiles
+
+	pdfCache map[string]*pdfResult // PDFs read, by site path
+	pdfMutex sync.Mutex            // Controls access to pdfCache
+
+	cssImportsCache map[string][]string // @imports of local stylesheets, by site path
+	cssImportsMutex sync.Mutex          // Controls access to cssImportsCache
+
+	traceExternal func(*http.Request) (*http.Response, error) // Replaces httpClient.Do when set, see why
+
+	hostHealthMap   map[string]*hostHealth // Connection failures, by host
+	hostHealthMutex sync.Mutex             // Controls access to hostHealthMap
+
+	failures         *failureSet // Errors found this run
+	previousFailures *failureSet // Errors found last run, with OnlyFailed
 }
 
 // Test : Given user options run htmltest and return a pointer to the test
 // object.
 func Test(optsUser map[string]interface{}) (*HTMLTest, error) {
-	hT := HTMLTest{}
+	return TestWithHooks(optsUser, Hooks{})
+}
+
+// TestWithHooks : As Test, calling hooks as the run progresses.
+func TestWithHooks(optsUser map[string]interface{}, hooks Hooks) (*HTMLTest, error) {
+	hT := HTMLTest{hooks: hooks}
 
 	// If FilePath set, modify FileExtension
 	if optsUser["FilePath"] != nil {
@@ -44,26 +81,43 @@ func Test(optsUser map[string]interface{}) (*HTMLTest, error) {
 	}
 
 	// Merge user options with defaults and set hT.opts
-	hT.setOptions(optsUser)
+	optsErr := hT.setOptions(optsUser)
+	timeStart := time.Now()
 
-	// Create issue store and set LogLevel and printImmediately if sort is seq
-	hT.issueStore = issues.NewIssueStore(hT.opts.LogLevel,
+	// Create issue store and set console level and printImmediately if sort is
+	// seq, then register file sinks
+	sinks, consoleLevel, sinksErr := hT.opts.outputSinks()
+	hT.issueStore = issues.NewIssueStore(consoleLevel,
 		(hT.opts.LogSort == "seq"))
-
-	transport := &http.Transport{
-		// Disable HTTP/2, this is required due to a number of edge cases where http negotiates H2, but something goes
-		// wrong when actually using it. Downgrading to H1 when this issue is hit is not yet supported so we use the
-		// following to disable H2 support:
-		// > Programs that must disable HTTP/2 can do so by setting Transport.TLSNextProto ... to a non-nil, empty map.
-		// See issue #49
-		TLSNextProto:    make(map[string]func(authority string, c *tls.Conn) http.RoundTripper),
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: hT.opts.IgnoreSSLVerify},
+	if optsErr != nil {
+		return &hT, optsErr
+	}
+	if sinksErr != nil {
+		return &hT, sinksErr
+	}
+	var rulesErr error
+	hT.dataFileRules, rulesErr = hT.opts.dataFileRules()
+	if rulesErr != nil {
+		return &hT, rulesErr
 	}
-	hT.httpClient = &http.Client{
-		// Durations are in nanoseconds
-		Transport: transport,
-		Timeout:   time.Duration(hT.opts.ExternalTimeout) * time.Second,
+	for _, sink := range sinks {
+		hT.issueStore.AddSink(sink)
 	}
+	if hT.opts.RetainAllIssues {
+		hT.issueStore.RetainLevel(issues.LevelDebug)
+	}
+	if hT.opts.IssueSpillThreshold > 0 {
+		os.MkdirAll(hT.opts.OutputDir, 0777)
+		spillErr := hT.issueStore.SetSpill(path.Join(hT.opts.OutputDir, hT.opts.OutputSpillFile),
+			hT.opts.IssueSpillThreshold)
+		if spillErr != nil {
+			return &hT, spillErr
+		}
+	}
+	hT.failures = newFailureSet()
+	hT.issueStore.SetIssueHook(hT.recordFailures(hooks.IssueAdded))
+
+	hT.setupHTTPClient()
 
 	// If enabled (unit tests only) patch in govcr to the httpClient
 	var vcr *govcr.VCRControlPanel
@@ -90,6 +144,11 @@ func Test(optsUser map[string]interface{}) (*HTMLTest, error) {
 		cachePath = path.Join(hT.opts.OutputDir, hT.opts.OutputCacheFile)
 	}
 	hT.refCache = refcache.NewRefCache(cachePath, hT.opts.CacheExpires)
+	if hT.opts.OnlyFailed {
+		if err := hT.loadPreviousFailures(sinks); err != nil {
+			return &hT, err
+		}
+	}
 
 	if hT.opts.NoRun {
 		return &hT, nil
@@ -125,9 +184,12 @@ func Test(optsUser map[string]interface{}) (*HTMLTest, error) {
 	hT.documentStore.DocumentExtension = hT.opts.FileExtension
 	hT.documentStore.DirectoryIndex = hT.opts.DirectoryIndex
 	hT.documentStore.IgnorePatterns = hT.opts.IgnoreDirs
+	hT.documentStore.IndexIgnored = hT.opts.IndexIgnoredDirs
+	hT.documentStore.IgnoreFilePatterns = hT.opts.IgnoreFiles
 	hT.documentStore.IgnoreTagAttribute = hT.opts.IgnoreTagAttribute
-	// Discover documents
-	hT.documentStore.Discover()
+	hT.documentStore.DiscoverConcurrency = hT.opts.DiscoverConcurrencyLimit
+	// Discover documents in the background, they're tested as they're found
+	hT.documentStore.DiscoverAsync()
 
 	if hT.opts.FilePath != "" {
 		// Single document mode
@@ -138,18 +200,49 @@ func Test(optsUser map[string]interface{}) (*HTMLTest, error) {
 			return &hT, err
 		}
 		hT.testDocument(doc)
+		hT.documentStore.WaitDiscovered()
+		hT.discoverIssues()
+		hT.checkPDFs()
+		hT.checkHosts()
 	} else if hT.opts.DirectoryPath != "" {
 		// Test documents
 		hT.testDocuments()
+		hT.discoverIssues()
+		hT.checkDataFiles()
+		hT.checkPDFs()
+		hT.checkHosts()
+		// Site level checks, these need every document tested
+		if hT.opts.CheckPagination && !hT.opts.OnlyFailed {
+			hT.checkPagination()
+		}
+		if hT.opts.CheckUnreferencedFiles && hT.opts.CheckInternal && !hT.opts.OnlyFailed {
+			hT.checkUnreferenced()
+		}
+		if !hT.opts.OnlyFailed {
+			hT.checkTranslations()
+		}
+		hT.saveFailures()
+	}
+
+	if stats := hT.HTTPStats(); stats.Requests > 0 {
+		hT.issueStore.AddIssue(issues.Issue{
+			Level: issues.LevelInfo,
+			Message: fmt.Sprintf("%d external requests, %d reused a connection, %d opened one",
+				stats.Requests, stats.ReusedConnections, stats.NewConnections),
+		})
 	}
 
 	if hT.opts.EnableCache {
 		hT.refCache.WriteStore(cachePath)
 	}
-	if hT.opts.EnableLog {
-		hT.issueStore.WriteLog(path.Join(hT.opts.OutputDir,
-			hT.opts.OutputLogFile))
-	}
+	hT.issueStore.WriteSinks(issues.RunMeta{
+		Version:       hT.opts.Version,
+		DirectoryPath: hT.opts.DirectoryPath,
+		Started:       timeStart,
+		Finished:      time.Now(),
+		Documents:     len(hT.documentStore.Documents),
+	})
+	hT.hooks.runFinished(&hT)
 
 	// This is useful for debugging the VCR, but rather noisy otherwise
 	//if hT.opts.VCREnable {
@@ -168,7 +261,11 @@ func (hT *HTMLTest) testDocuments() {
 		var wg sync.WaitGroup
 		// Make buffered channel to act as concurrency limiter
 		var concChannel = make(chan bool, hT.opts.DocumentConcurrencyLimit)
-		for _, document := range hT.documentStore.Documents {
+		for i := 0; ; i++ {
+			document, ok := hT.documentStore.DocumentAt(i)
+			if !ok {
+				break
+			}
 			wg.Add(1)
 			concChannel <- true // Add to concurrency limiter
 			go func(document *htmldoc.Document) {
@@ -179,13 +276,39 @@ func (hT *HTMLTest) testDocuments() {
 		}
 		wg.Wait()
 	} else {
-		for _, document := range hT.documentStore.Documents {
+		for i := 0; ; i++ {
+			document, ok := hT.documentStore.DocumentAt(i)
+			if !ok {
+				break
+			}
 			hT.testDocument(document)
 		}
 	}
 }
 
+// Report paths discovery couldn't read, call once discovery has finished.
+func (hT *HTMLTest) discoverIssues() {
+	for _, err := range hT.documentStore.DiscoverErrors {
+		hT.issueStore.AddIssue(issues.Issue{
+			Level:   issues.LevelError,
+			Message: "cannot read " + err.Error(),
+			Check:   "Discovery",
+		})
+	}
+}
+
 func (hT *HTMLTest) testDocument(document *htmldoc.Document) {
+	if !hT.onlyFailedSelected(document) {
+		hT.issueStore.AddIssue(issues.Issue{
+			Level:   issues.LevelDebug,
+			Message: "skipping " + document.SitePath + ", passed last run",
+		})
+		return
+	}
+
+	hT.hooks.documentStarted(document)
+	defer hT.hooks.documentFinished(document)
+
 	hT.issueStore.AddIssue(issues.Issue{
 		Level:   issues.LevelDebug,
 		Message: "testDocument on " + document.SitePath,
@@ -197,7 +320,34 @@ func (hT *HTMLTest) testDocument(document *htmldoc.Document) {
 		hT.checkDoctype(document)
 	}
 
+	if hT.opts.CheckSecrets {
+		hT.checkSecrets(document)
+	}
+
+	if hT.opts.CheckRenderBlocking {
+		hT.checkRenderBlocking(document)
+	}
+
+	if hT.opts.CheckTables {
+		hT.checkTables(document)
+	}
+
+	if hT.opts.CheckLandmarks {
+		hT.checkLandmarks(document)
+	}
+
+	if hT.opts.CheckSkipLinks {
+		hT.checkSkipLink(document)
+	}
+
+	if hT.opts.Profile == profileEmail {
+		hT.checkEmailSize(document)
+	}
+
 	for _, n := range document.NodesOfInterest {
+		if hT.opts.Profile == profileEmail {
+			hT.checkEmailNode(document, n)
+		}
 		switch n.Data {
 		case "a":
 			if hT.opts.CheckAnchors {
@@ -212,7 +362,8 @@ func (hT *HTMLTest) testDocument(document *htmldoc.Document) {
 				hT.checkImg(document, n)
 			}
 		case "script":
-			if hT.opts.CheckScripts {
+			// Scripts are reported by checkEmailNode, not checked, in email
+			if hT.opts.CheckScripts && hT.opts.Profile != profileEmail {
 				hT.checkScript(document, n)
 			}
 		case "meta":
@@ -256,6 +407,7 @@ func (hT *HTMLTest) postChecks(document *htmldoc.Document) {
 		hT.issueStore.AddIssue(issues.Issue{
 			Level:   issues.LevelError,
 			Message: "favicon missing",
+			Check:   "Favicon",
 		})
 	}
 }
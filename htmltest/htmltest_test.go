@@ -2,6 +2,10 @@ package htmltest
 
 import (
 	"github.com/daviddengcn/go-assert"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
 	"testing"
 )
 
@@ -91,6 +95,27 @@ func TestCountErrors(t *testing.T) {
 	assert.Equals(t, "CountErrors", hT.CountErrors(), 2)
 }
 
+func TestIssueSpill(t *testing.T) {
+	dir, err := ioutil.TempDir("", "htmltest-spill")
+	assert.Equals(t, "tempdir error", err, nil)
+	defer os.RemoveAll(dir)
+
+	hT := tTestDirectoryOpts("fixtures/documents/folder-not-ok", map[string]interface{}{
+		"OutputDir":           dir,
+		"IssueSpillThreshold": 1,
+		"OutputSinks": []interface{}{
+			map[string]interface{}{"Path": "errors.log", "Level": "error"},
+		},
+	})
+	assert.Equals(t, "CountErrors", hT.CountErrors(), 2)
+	// Spilled issues still reach sinks, then the file is removed
+	_, err = os.Stat(path.Join(dir, "issues.spill"))
+	assert.IsTrue(t, "spill removed", os.IsNotExist(err))
+	log, err := ioutil.ReadFile(path.Join(dir, "errors.log"))
+	assert.Equals(t, "log error", err, nil)
+	assert.Equals(t, "log errors", strings.Count(string(log), " ERROR "), 2)
+}
+
 func TestFileExtensionDefault(t *testing.T) {
 	// Non .html files are ignored
 	hT := tTestDirectory("fixtures/documents/folder-htm")
@@ -121,7 +146,8 @@ func TestCacheIntegration(t *testing.T) {
 
 func TestConcurrencyDirExternals(t *testing.T) {
 	tSkipShortExternal(t)
+	// Every URL counted on its own, even if github.com can't be reached
 	hT := tTestDirectoryOpts("fixtures/concurrency/manyBrokenExt",
-		map[string]interface{}{"TestFilesConcurrently": true}) // "LogLevel": 1
+		map[string]interface{}{"TestFilesConcurrently": true, "HostFailureThreshold": 0}) // "LogLevel": 1
 	tExpectIssueCount(t, hT, 26)
 }
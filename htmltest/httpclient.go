@@ -0,0 +1,106 @@
+package htmltest
+
+import (
+	"crypto/tls"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// HTTPStats : Counts of external requests made and how their connections were
+// got. Redirects count as requests of their own.
+type HTTPStats struct {
+	Requests          int64
+	ReusedConnections int64 // Served by an idle keep-alive connection
+	NewConnections    int64 // Needed a fresh connection to be dialled
+}
+
+// statsTransport : RoundTripper counting requests and connection reuse into
+// stats before handing them to base
+type statsTransport struct {
+	base  http.RoundTripper
+	stats *HTTPStats
+}
+
+func (sT *statsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	atomic.AddInt64(&sT.stats.Requests, 1)
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if info.Reused {
+				atomic.AddInt64(&sT.stats.ReusedConnections, 1)
+			} else {
+				atomic.AddInt64(&sT.stats.NewConnections, 1)
+			}
+		},
+	}
+	return sT.base.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+}
+
+// Build the transport and client external requests are made with. The idle
+// pool holds a connection for every request HTTPConcurrencyLimit allows at
+// once, so keep-alive connections are reused rather than redialled.
+func (hT *HTMLTest) setupHTTPClient() {
+	hT.httpStats = &HTTPStats{}
+	hT.httpTransport = &http.Transport{
+		// Disable HTTP/2, this is required due to a number of edge cases where http negotiates H2, but something goes
+		// wrong when actually using it. Downgrading to H1 when this issue is hit is not yet supported so we use the
+		// following to disable H2 support:
+		// > Programs that must disable HTTP/2 can do so by setting Transport.TLSNextProto ... to a non-nil, empty map.
+		// See issue #49
+		TLSNextProto:    make(map[string]func(authority string, c *tls.Conn) http.RoundTripper),
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: hT.opts.IgnoreSSLVerify},
+		DialContext: (&net.Dialer{
+			Timeout:   time.Duration(hT.opts.ExternalDialTimeout) * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSHandshakeTimeout:   time.Duration(hT.opts.ExternalTLSTimeout) * time.Second,
+		ResponseHeaderTimeout: time.Duration(hT.opts.ExternalHeaderTimeout) * time.Second,
+		MaxIdleConns:          hT.opts.HTTPConcurrencyLimit,
+		MaxIdleConnsPerHost:   hT.opts.HTTPConcurrencyLimit,
+		IdleConnTimeout:       90 * time.Second,
+	}
+	hT.httpClient = &http.Client{
+		Transport: &statsTransport{base: hT.httpTransport, stats: hT.httpStats},
+		// Durations are in nanoseconds
+		Timeout: time.Duration(hT.opts.ExternalTimeout) * time.Second,
+	}
+}
+
+// Drain up to ExternalMaxBodyBytes of resp's body so its connection can go
+// back to the idle pool, then close it. Larger bodies are closed part read,
+// dropping the connection rather than downloading them.
+func (hT *HTMLTest) closeBody(resp *http.Response) {
+	io.CopyN(ioutil.Discard, resp.Body, int64(hT.opts.ExternalMaxBodyBytes))
+	resp.Body.Close()
+}
+
+// Describe errors from the transport's own timeouts, empty for other errors.
+func transportTimeoutMessage(err error) string {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "TLS handshake timeout"):
+		return "TLS handshake exceeded our ExternalTLSTimeout"
+	case strings.Contains(msg, "timeout awaiting response headers"):
+		return "response headers exceeded our ExternalHeaderTimeout"
+	case strings.Contains(msg, "dial tcp") && strings.Contains(msg, "i/o timeout"):
+		return "connecting exceeded our ExternalDialTimeout"
+	}
+	return ""
+}
+
+// HTTPStats : Counts of the external requests made so far.
+func (hT *HTMLTest) HTTPStats() HTTPStats {
+	if hT.httpStats == nil {
+		return HTTPStats{}
+	}
+	return HTTPStats{
+		Requests:          atomic.LoadInt64(&hT.httpStats.Requests),
+		ReusedConnections: atomic.LoadInt64(&hT.httpStats.ReusedConnections),
+		NewConnections:    atomic.LoadInt64(&hT.httpStats.NewConnections),
+	}
+}
@@ -0,0 +1,86 @@
+package htmltest
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daviddengcn/go-assert"
+)
+
+func TestHTTPConnectionReuse(t *testing.T) {
+	// bodies are drained so one connection serves every request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 1024)))
+	}))
+	defer server.Close()
+
+	hT := tTestExternalLinks(server.URL, tPaths(5), map[string]interface{}{})
+	tExpectIssueCount(t, hT, 0)
+	stats := hT.HTTPStats()
+	assert.Equals(t, "requests", stats.Requests, int64(5))
+	assert.Equals(t, "new", stats.NewConnections, int64(1))
+	assert.Equals(t, "reused", stats.ReusedConnections, int64(4))
+}
+
+func TestHTTPLargeBodyNotDrained(t *testing.T) {
+	// bodies beyond ExternalMaxBodyBytes aren't downloaded, so connections
+	// can't be reused
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 1024*1024)))
+	}))
+	defer server.Close()
+
+	hT := tTestExternalLinks(server.URL, tPaths(3), map[string]interface{}{"ExternalMaxBodyBytes": 1024})
+	tExpectIssueCount(t, hT, 0)
+	stats := hT.HTTPStats()
+	assert.Equals(t, "new", stats.NewConnections, int64(3))
+	assert.Equals(t, "reused", stats.ReusedConnections, int64(0))
+}
+
+func TestHTTPHeaderTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(1500 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	hT := tTestExternalLinks(server.URL, []string{"/slow"}, map[string]interface{}{
+		"ExternalTimeout":       10,
+		"ExternalHeaderTimeout": 1,
+	})
+	tExpectIssueCount(t, hT, 1)
+	tExpectIssue(t, hT, "response headers exceeded our ExternalHeaderTimeout", 1)
+}
+
+func TestHTTPTLSTimeout(t *testing.T) {
+	// a server which accepts connections and never answers
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Equals(t, "listen", err, nil)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	hT := tTestExternalLinks("https://"+listener.Addr().String(), []string{"/"}, map[string]interface{}{
+		"ExternalTimeout":    10,
+		"ExternalTLSTimeout": 1,
+	})
+	tExpectIssueCount(t, hT, 1)
+	tExpectIssue(t, hT, "TLS handshake exceeded our ExternalTLSTimeout", 1)
+}
+
+func TestHTTPPoolSize(t *testing.T) {
+	hT := tTestFileOpts("fixtures/links/head_link_href.html",
+		map[string]interface{}{"HTTPConcurrencyLimit": 7, "NoRun": true})
+	assert.Equals(t, "max idle", hT.httpTransport.MaxIdleConns, 7)
+	assert.Equals(t, "max idle per host", hT.httpTransport.MaxIdleConnsPerHost, 7)
+}
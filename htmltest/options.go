@@ -14,6 +14,8 @@ import (
 // Options struct for htmltest, user and default options are merged and mapped
 // into an instance of this struct.
 type Options struct {
+	Preset string // Name of a static site generator preset, see presets
+
 	DirectoryPath  string
 	DirectoryIndex string
 	FilePath       string
@@ -27,20 +29,49 @@ type Options struct {
 	CheckMeta    bool
 	CheckGeneric bool
 
-	CheckExternal     bool
-	CheckInternal     bool
-	CheckInternalHash bool
-	CheckMailto       bool
-	CheckTel          bool
-	CheckFavicon      bool
-	CheckMetaRefresh  bool
+	CheckExternal          bool
+	CheckInternal          bool
+	CheckInternalHash      bool
+	CheckMailto            bool
+	CheckTel               bool
+	CheckFavicon           bool
+	CheckMetaRefresh       bool
+	CheckSourceMaps        bool
+	CheckPDFs              bool
+	ForbidSourceMaps       bool
+	CheckSecrets           bool
+	CheckRenderBlocking    bool
+	CheckPagination        bool
+	CheckImageMaps         bool
+	CheckTables            bool
+	CheckLandmarks         bool
+	CheckSkipLinks         bool
+	CheckUnreferencedFiles bool
+	WarnLegacyAnchors      bool
 
 	EnforceHTML5 bool
 	EnforceHTTPS bool
 
+	Profile       string // "web" or "email", see check-email.go
+	EmailMaxBytes int
+
 	IgnoreURLs         []interface{}
 	IgnoreInternalURLs []interface{}
 	IgnoreDirs         []interface{}
+	IgnoreFiles        []interface{}
+	IndexIgnoredDirs   bool // Read IgnoreDirs during discovery so references into them resolve from memory
+
+	Languages          []interface{}
+	LanguageRoots      map[interface{}]interface{}
+	IgnoreTranslations []interface{}
+	IgnoreUnreferenced []interface{}
+
+	SecretPatterns     []interface{}
+	SecretEmailDomains []interface{}
+
+	MaxBlockingStylesheets int
+	MaxInlineScriptBytes   int
+	MaxInlineStyleBytes    int
 
 	IgnoreInternalEmptyHash             bool
 	IgnoreEmptyHref                     bool
@@ -53,16 +84,35 @@ type Options struct {
 
 	HTTPHeaders map[interface{}]interface{}
 
+	BotBlockStatuses []interface{}
+	BotBlockedLevel  int
+
+	SuggestArchivedLinks   bool
+	ArchiveAvailabilityURL string
+
 	TestFilesConcurrently    bool
 	DocumentConcurrencyLimit int
 	HTTPConcurrencyLimit     int
+	DiscoverConcurrencyLimit int
 
 	LogLevel int
 	LogSort  string
 
-	ExternalTimeout    int
-	StripQueryString   bool
-	StripQueryExcludes []interface{}
+	OutputSinks []interface{} // Extra outputs, see outputSinks
+
+	DataFiles []interface{} // Data files to check URLs in, see dataFileRules
+	PDFFiles  []interface{} // Globs of PDFs to check along with those referenced
+
+	OnlyFailed bool // Only re-check what failed last run, see failures.go
+
+	ExternalTimeout       int
+	ExternalDialTimeout   int
+	ExternalTLSTimeout    int
+	ExternalHeaderTimeout int
+	ExternalMaxBodyBytes  int
+	HostFailureThreshold  int // Connection failures in a row before a host is down, 0 to disable
+	StripQueryString      bool
+	StripQueryExcludes    []interface{}
 
 	EnableCache     bool
 	EnableLog       bool
@@ -71,16 +121,26 @@ type Options struct {
 	OutputLogFile   string
 	CacheExpires    string // Accepts golang time period strings, hours (16h) is really only useful option
 
+	EnableMarkdown     bool
+	OutputMarkdownFile string
+	MarkdownMaxBytes   int // Truncate the Markdown report beyond this size, 0 to disable
+
+	IssueSpillThreshold int    // Retained issues held in memory before spilling to disk, 0 to disable
+	OutputSpillFile     string // Within OutputDir
+
 	// --- Internals below here ---
-	NoRun     bool   // When true does not run tests, used to inspect state in unit tests
-	VCREnable bool   // When true patches the govcr httpClient to mock network calls
-	Version   string // Instigator should set this to a version string
+	NoRun           bool   // When true does not run tests, used to inspect state in unit tests
+	VCREnable       bool   // When true patches the govcr httpClient to mock network calls
+	RetainAllIssues bool   // When true keeps every issue for DumpIssues, used in unit tests
+	Version         string // Instigator should set this to a version string
 }
 
 // DefaultOptions returns a map of default options.
 func DefaultOptions() map[string]interface{} {
 	// Specify defaults here
 	return map[string]interface{}{
+		"Preset": "",
+
 		"DirectoryIndex": "index.html",
 		"FileExtension":  ".html",
 
@@ -92,20 +152,50 @@ func DefaultOptions() map[string]interface{} {
 		"CheckMeta":    true,
 		"CheckGeneric": true,
 
-		"CheckExternal":     true,
-		"CheckInternal":     true,
-		"CheckInternalHash": true,
-		"CheckMailto":       true,
-		"CheckTel":          true,
-		"CheckFavicon":      false,
-		"CheckMetaRefresh":  true,
+		"CheckExternal":          true,
+		"CheckInternal":          true,
+		"CheckInternalHash":      true,
+		"CheckMailto":            true,
+		"CheckTel":               true,
+		"CheckFavicon":           false,
+		"CheckMetaRefresh":       true,
+		"CheckSourceMaps":        false,
+		"CheckPDFs":              false,
+		"ForbidSourceMaps":       false,
+		"CheckSecrets":           false,
+		"CheckRenderBlocking":    false,
+		"CheckPagination":        false,
+		"CheckImageMaps":         false,
+		"CheckTables":            false,
+		"CheckLandmarks":         false,
+		"CheckSkipLinks":         false,
+		"CheckUnreferencedFiles": false,
+		"WarnLegacyAnchors":      false,
 
 		"EnforceHTML5": false,
 		"EnforceHTTPS": false,
 
+		"Profile":       profileWeb,
+		"EmailMaxBytes": 102400, // Gmail clips messages beyond 102KB
+
 		"IgnoreURLs":         []interface{}{},
 		"IgnoreInternalURLs": []interface{}{},
 		"IgnoreDirs":         []interface{}{},
+		"IgnoreFiles":        []interface{}{},
+		"IndexIgnoredDirs":   false,
+
+		"Languages":          []interface{}{},
+		"LanguageRoots":      map[interface{}]interface{}{},
+		"IgnoreTranslations": []interface{}{},
+		"IgnoreUnreferenced": []interface{}{},
+
+		"SecretPatterns":     []interface{}{},
+		"SecretEmailDomains": []interface{}{},
+
+		// About 14KB fits in the first round trip of a new connection
+		"MaxBlockingStylesheets": 3,
+		"MaxInlineScriptBytes":   14336,
+		"MaxInlineStyleBytes":    14336,
 
 		"IgnoreInternalEmptyHash":             false,
 		"IgnoreEmptyHref":                     false,
@@ -121,16 +211,35 @@ func DefaultOptions() map[string]interface{} {
 			"Accept": "*/*",       // We accept all content types
 		},
 
+		"BotBlockStatuses": []interface{}{403, 429, 999},
+		"BotBlockedLevel":  issues.LevelWarning,
+
+		"SuggestArchivedLinks":   false,
+		"ArchiveAvailabilityURL": "https://archive.org/wayback/available",
+
 		"TestFilesConcurrently":    false,
 		"DocumentConcurrencyLimit": 128,
 		"HTTPConcurrencyLimit":     16,
+		"DiscoverConcurrencyLimit": 32,
 
 		"LogLevel": issues.LevelWarning,
 		"LogSort":  "document",
 
-		"ExternalTimeout":    15,
-		"StripQueryString":   true,
-		"StripQueryExcludes": []interface{}{"fonts.googleapis.com"},
+		"OutputSinks": []interface{}{},
+
+		"DataFiles": []interface{}{},
+		"PDFFiles":  []interface{}{},
+
+		"OnlyFailed": false,
+
+		"ExternalTimeout":       15,
+		"ExternalDialTimeout":   10,
+		"ExternalTLSTimeout":    10,
+		"ExternalHeaderTimeout": 15,
+		"ExternalMaxBodyBytes":  65536,
+		"HostFailureThreshold":  5,
+		"StripQueryString":      true,
+		"StripQueryExcludes":    []interface{}{"fonts.googleapis.com"},
 
 		"EnableCache":     true,
 		"EnableLog":       true,
@@ -139,18 +248,32 @@ func DefaultOptions() map[string]interface{} {
 		"OutputLogFile":   "htmltest.log",
 		"CacheExpires":    "336h",
 
-		"NoRun":     false,
-		"VCREnable": false,
-		"Version":   "dev",
+		"EnableMarkdown":     false,
+		"OutputMarkdownFile": "htmltest.md",
+		"MarkdownMaxBytes":   65000,
+
+		"IssueSpillThreshold": 0,
+		"OutputSpillFile":     "issues.spill",
+
+		"NoRun":           false,
+		"VCREnable":       false,
+		"RetainAllIssues": false,
+		"Version":         "dev",
 	}
 }
 
-func (hT *HTMLTest) setOptions(optsUser map[string]interface{}) {
-	// Merge user and default options, set Opts var
+func (hT *HTMLTest) setOptions(optsUser map[string]interface{}) error {
+	// Merge default, preset and user options, set Opts var
 	optsMap := DefaultOptions()
+	preset, _ := optsUser["Preset"].(string)
+	optsPreset, err := PresetOptions(preset)
+	mergo.Merge(&optsMap, optsPreset, mergo.WithOverride)
 	mergo.Merge(&optsMap, optsUser, mergo.WithOverride)
 	hT.opts = Options{}
 	mergo.Map(&hT.opts, optsMap, mergo.WithOverride)
+	if err == nil && hT.opts.Profile != profileWeb && hT.opts.Profile != profileEmail {
+		err = fmt.Errorf("unknown Profile %q, expected %s or %s", hT.opts.Profile, profileWeb, profileEmail)
+	}
 
 	// If debug dump the options struct
 	if hT.opts.LogLevel == issues.LevelDebug {
@@ -163,6 +286,7 @@ func (hT *HTMLTest) setOptions(optsUser map[string]interface{}) {
 				typeOfT.Field(i).Name, f.Type(), f.Interface())
 		}
 	}
+	return err
 }
 
 // InList tests if key is in a slice/list.
@@ -185,6 +309,27 @@ func (opts *Options) isURLIgnored(url string) bool {
 	return false
 }
 
+// Is the given unreferenced file ignored by the current configuration
+func (opts *Options) isUnreferencedIgnored(sitePath string) bool {
+	for _, item := range opts.IgnoreUnreferenced {
+		if ok, _ := regexp.MatchString(item.(string), sitePath); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Is the given page, relative to its language root, ignored by the current
+// configuration
+func (opts *Options) isTranslationIgnored(rel string) bool {
+	for _, item := range opts.IgnoreTranslations {
+		if ok, _ := regexp.MatchString(item.(string), rel); ok {
+			return true
+		}
+	}
+	return false
+}
+
 // Solve #168
 // Is the given local URL ignored by the current configuration
 func (opts *Options) isInternalURLIgnored(url string) bool {
@@ -195,3 +340,132 @@ func (opts *Options) isInternalURLIgnored(url string) bool {
 	}
 	return false
 }
+
+// Build file sinks from EnableLog, EnableMarkdown and OutputSinks, and find
+// the console level. OutputSinks entries are maps with a Type of "file"
+// (default) or "console", and optional Path, Level, Format and MaxBytes. File
+// paths are relative to OutputDir.
+func (opts *Options) outputSinks() ([]issues.Sink, int, error) {
+	consoleLevel := opts.LogLevel
+	sinks := make([]issues.Sink, 0)
+	if opts.EnableLog {
+		sinks = append(sinks, issues.Sink{
+			Path:   path.Join(opts.OutputDir, opts.OutputLogFile),
+			Level:  opts.LogLevel,
+			Format: issues.FormatText,
+		})
+	}
+	if opts.EnableMarkdown {
+		sinks = append(sinks, issues.Sink{
+			Path:     path.Join(opts.OutputDir, opts.OutputMarkdownFile),
+			Level:    opts.LogLevel,
+			Format:   issues.FormatMarkdown,
+			MaxBytes: opts.MarkdownMaxBytes,
+		})
+	}
+
+	for i, item := range opts.OutputSinks {
+		entry := make(map[string]interface{})
+		switch m := item.(type) {
+		case map[interface{}]interface{}:
+			for k, v := range m {
+				entry[fmt.Sprintf("%v", k)] = v
+			}
+		case map[string]interface{}:
+			entry = m
+		default:
+			return nil, consoleLevel, fmt.Errorf("OutputSinks entry %d is not a map", i)
+		}
+
+		sink := issues.Sink{
+			Level:    opts.LogLevel,
+			Format:   issues.FormatText,
+			MaxBytes: opts.MarkdownMaxBytes,
+		}
+		if v, ok := entry["Level"]; ok {
+			level, err := issues.ParseLevel(v)
+			if err != nil {
+				return nil, consoleLevel, fmt.Errorf("OutputSinks entry %d: %s", i, err)
+			}
+			sink.Level = level
+		}
+		if v, ok := entry["Format"]; ok {
+			sink.Format = fmt.Sprintf("%v", v)
+			if !issues.ValidFormat(sink.Format) {
+				return nil, consoleLevel, fmt.Errorf("OutputSinks entry %d: invalid format %q", i, sink.Format)
+			}
+		}
+		if v, ok := entry["MaxBytes"].(int); ok {
+			sink.MaxBytes = v
+		}
+
+		switch entry["Type"] {
+		case "console":
+			if sink.Format != issues.FormatText {
+				return nil, consoleLevel, fmt.Errorf("OutputSinks entry %d: console only supports text", i)
+			}
+			consoleLevel = sink.Level
+		case nil, "file":
+			p, _ := entry["Path"].(string)
+			if p == "" {
+				return nil, consoleLevel, fmt.Errorf("OutputSinks entry %d: file sink needs a Path", i)
+			}
+			sink.Path = path.Join(opts.OutputDir, p)
+			sinks = append(sinks, sink)
+		default:
+			return nil, consoleLevel, fmt.Errorf("OutputSinks entry %d: unknown type %v", i, entry["Type"])
+		}
+	}
+	return sinks, consoleLevel, nil
+}
+
+// Build data file rules from DataFiles. Entries are maps with a Glob matched
+// against site paths, ** spanning directories, and Fields, a list of
+// selectors as understood by parseDataPath.
+func (opts *Options) dataFileRules() ([]dataFileRule, error) {
+	rules := make([]dataFileRule, 0)
+	for i, item := range opts.DataFiles {
+		entry := make(map[string]interface{})
+		switch m := item.(type) {
+		case map[interface{}]interface{}:
+			for k, v := range m {
+				entry[fmt.Sprintf("%v", k)] = v
+			}
+		case map[string]interface{}:
+			entry = m
+		default:
+			return nil, fmt.Errorf("DataFiles entry %d is not a map", i)
+		}
+
+		glob, _ := entry["Glob"].(string)
+		if glob == "" {
+			return nil, fmt.Errorf("DataFiles entry %d: needs a Glob", i)
+		}
+		rule := dataFileRule{glob: glob, globRegexp: globToRegexp(glob)}
+
+		var fields []interface{}
+		switch v := entry["Fields"].(type) {
+		case []interface{}:
+			fields = v
+		case []string:
+			for _, field := range v {
+				fields = append(fields, field)
+			}
+		case string:
+			fields = []interface{}{v}
+		}
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("DataFiles entry %d: needs Fields", i)
+		}
+		for _, field := range fields {
+			steps, err := parseDataPath(fmt.Sprintf("%v", field))
+			if err != nil {
+				return nil, fmt.Errorf("DataFiles entry %d: %s", i, err)
+			}
+			rule.fields = append(rule.fields, fmt.Sprintf("%v", field))
+			rule.steps = append(rule.steps, steps)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
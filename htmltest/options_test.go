@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/daviddengcn/go-assert"
+	"github.com/wjdp/htmltest/issues"
 	"github.com/wjdp/htmltest/output"
 )
 
@@ -87,3 +88,39 @@ func TestMergeHTTPHeaders(t *testing.T) {
 
 	assert.Equals(t, "url ignored", hT.opts.HTTPHeaders["Range"], "bytes=0-10")
 }
+
+func TestOutputSinks(t *testing.T) {
+	hT, err := Test(map[string]interface{}{
+		"LogLevel":  issues.LevelWarning,
+		"OutputDir": "out",
+		"OutputSinks": []interface{}{
+			map[interface{}]interface{}{"Type": "console", "Level": "error"},
+			map[interface{}]interface{}{"Path": "debug.log", "Level": 0},
+			map[string]interface{}{"Path": "report.json", "Format": "json"},
+		},
+		"NoRun": true,
+	})
+	output.CheckErrorPanic(err)
+
+	sinks, consoleLevel, err := hT.opts.outputSinks()
+	assert.Equals(t, "error", err, nil)
+	assert.Equals(t, "console level", consoleLevel, issues.LevelError)
+	assert.Equals(t, "sink count", len(sinks), 3)
+	assert.Equals(t, "default log", sinks[0].Path, "out/htmltest.log")
+	assert.Equals(t, "default log level", sinks[0].Level, issues.LevelWarning)
+	assert.Equals(t, "debug sink level", sinks[1].Level, issues.LevelDebug)
+	assert.Equals(t, "json sink path", sinks[2].Path, "out/report.json")
+	assert.Equals(t, "json sink format", sinks[2].Format, issues.FormatJSON)
+	assert.Equals(t, "json sink level", sinks[2].Level, issues.LevelWarning)
+}
+
+func TestOutputSinksInvalid(t *testing.T) {
+	_, err := Test(map[string]interface{}{
+		"OutputSinks": []interface{}{
+			map[string]interface{}{"Format": "json"},
+		},
+		"NoRun": true,
+	})
+	assert.NotEquals(t, "Error", err, nil)
+	assert.Equals(t, "Error", err.Error(), "OutputSinks entry 0: file sink needs a Path")
+}
@@ -0,0 +1,108 @@
+package htmltest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Option bundles for popular static site generators, applied on top of
+// DefaultOptions and beneath user options when Preset is set.
+var presets = map[string]map[string]interface{}{
+	"hugo": {
+		"DirectoryPath": "public",
+	},
+	"jekyll": {
+		"DirectoryPath": "_site",
+		// GitHub Pages redirects /about to /about/
+		"IgnoreDirectoryMissingTrailingSlash": true,
+	},
+	"docusaurus": {
+		"DirectoryPath": "build",
+		// Hash routes and tab anchors only exist once the client side app runs
+		"CheckInternalHash":                   false,
+		"IgnoreInternalEmptyHash":             true,
+		"IgnoreDirectoryMissingTrailingSlash": true,
+		"IgnoreDirs":                          []interface{}{"^assets/"},
+		// Every page loads its scripts and styles from there
+		"IndexIgnoredDirs": true,
+	},
+	"mkdocs": {
+		"DirectoryPath": "site",
+		"IgnoreDirs":    []interface{}{"^search/"},
+		// 404.html links are relative to wherever it's served from
+		"IgnoreFiles": []interface{}{"^404\\.html$"},
+	},
+	"eleventy": {
+		"DirectoryPath": "_site",
+	},
+	"sphinx": {
+		"DirectoryPath": "_build/html",
+		"IgnoreDirs":    []interface{}{"^_static/", "^_sources/"},
+		// Every page loads its scripts and styles from _static
+		"IndexIgnoredDirs": true,
+		// Filled in by JavaScript
+		"IgnoreFiles": []interface{}{"^search\\.html$"},
+	},
+}
+
+// Files in a project root that identify its generator, in the order they're
+// tried. Names shared with other tools, such as conf.py, must also contain
+// the given text, case insensitive.
+var presetMarkers = []struct {
+	preset   string
+	files    []string
+	contains string
+}{
+	{"docusaurus", []string{"docusaurus.config.js", "docusaurus.config.ts", "docusaurus.config.mjs"}, ""},
+	{"mkdocs", []string{"mkdocs.yml", "mkdocs.yaml"}, ""},
+	{"hugo", []string{"hugo.toml", "hugo.yaml", "hugo.yml", "hugo.json"}, ""},
+	{"eleventy", []string{".eleventy.js", "eleventy.config.js", "eleventy.config.mjs", "eleventy.config.cjs"}, ""},
+	{"jekyll", []string{"Gemfile"}, "jekyll"},
+	{"sphinx", []string{"conf.py"}, "sphinx"},
+}
+
+// PresetNames : Names of the available presets, sorted.
+func PresetNames() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DetectPreset : Guess the preset for the project in dir from the config
+// files its generator uses. Empty if none match.
+func DetectPreset(dir string) string {
+	for _, marker := range presetMarkers {
+		for _, file := range marker.files {
+			b, err := ioutil.ReadFile(path.Join(dir, file))
+			if err == nil && strings.Contains(strings.ToLower(string(b)), marker.contains) {
+				return marker.preset
+			}
+		}
+	}
+	return ""
+}
+
+// PresetOptions : Options of the named preset, an empty name or "none" has
+// none.
+func PresetOptions(name string) (map[string]interface{}, error) {
+	if name == "" || name == "none" {
+		return map[string]interface{}{}, nil
+	}
+	opts, ok := presets[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown Preset %q, expected one of: %s",
+			name, strings.Join(PresetNames(), ", "))
+	}
+	// Copy so merging can't alter the preset
+	copied := make(map[string]interface{}, len(opts))
+	for key, value := range opts {
+		copied[key] = value
+	}
+	return copied, nil
+}
@@ -0,0 +1,74 @@
+package htmltest
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/daviddengcn/go-assert"
+	"github.com/wjdp/htmltest/output"
+)
+
+func TestPresetApplied(t *testing.T) {
+	// preset options sit between defaults and user options
+	hT, err := Test(map[string]interface{}{
+		"Preset":     "docusaurus",
+		"IgnoreDirs": []interface{}{"^img/"},
+		"NoRun":      true,
+	})
+	output.CheckErrorPanic(err)
+	assert.Equals(t, "preset DirectoryPath", hT.opts.DirectoryPath, "build")
+	assert.Equals(t, "preset CheckInternalHash", hT.opts.CheckInternalHash, false)
+	assert.Equals(t, "preset IndexIgnoredDirs", hT.opts.IndexIgnoredDirs, true)
+	assert.StringEquals(t, "user IgnoreDirs", hT.opts.IgnoreDirs, []interface{}{"^img/"})
+	assert.Equals(t, "default CheckExternal", hT.opts.CheckExternal, true)
+}
+
+func TestPresetNone(t *testing.T) {
+	// none leaves the defaults alone
+	hT, err := Test(map[string]interface{}{"Preset": "none", "NoRun": true})
+	output.CheckErrorPanic(err)
+	assert.Equals(t, "DirectoryPath", hT.opts.DirectoryPath, "")
+}
+
+func TestPresetUnknown(t *testing.T) {
+	// unknown presets are an error listing those available
+	_, err := Test(map[string]interface{}{"Preset": "frontpage", "NoRun": true})
+	assert.NotEquals(t, "error", err, nil)
+	assert.Equals(t, "error message", err.Error(), "unknown Preset \"frontpage\", expected one of: "+
+		"docusaurus, eleventy, hugo, jekyll, mkdocs, sphinx")
+}
+
+func TestPresetMkDocsIgnoresFiles(t *testing.T) {
+	// the mkdocs preset skips 404.html and the search index
+	hT := tTestDirectoryOpts("fixtures/presets/mkdocs/site", map[string]interface{}{"Preset": "mkdocs"})
+	tExpectIssueCount(t, hT, 0)
+	assert.Equals(t, "document count", hT.CountDocuments(), 1)
+}
+
+func TestDetectPreset(t *testing.T) {
+	// generators are detected from their config files
+	dir, err := ioutil.TempDir("", "htmltest-presets")
+	output.CheckErrorPanic(err)
+	defer os.RemoveAll(dir)
+
+	assert.Equals(t, "empty dir", DetectPreset(dir), "")
+	cases := []struct{ file, content, preset string }{
+		// Shared names need content pointing at the generator
+		{"conf.py", "project = 'tool'", ""},
+		{"_config.yml", "", ""},
+		{"Gemfile", "gem \"rails\"", ""},
+		{"conf.py", "extensions = ['sphinx.ext.autodoc']", "sphinx"},
+		{"Gemfile", "gem \"jekyll\"", "jekyll"},
+		{"hugo.toml", "", "hugo"},
+		{"mkdocs.yml", "", "mkdocs"},
+		{"docusaurus.config.js", "", "docusaurus"},
+	}
+	// Each marker outranks those before it
+	for _, c := range cases {
+		err := ioutil.WriteFile(path.Join(dir, c.file), []byte(c.content), 0644)
+		output.CheckErrorPanic(err)
+		assert.Equals(t, c.file, DetectPreset(dir), c.preset)
+	}
+}
@@ -1,10 +1,14 @@
 package htmltest
 
 import (
+	"fmt"
 	"github.com/imdario/mergo"
 	"github.com/wjdp/htmltest/issues"
 	"github.com/wjdp/htmltest/output"
+	"io/ioutil"
+	"os"
 	"path"
+	"strconv"
 	"testing"
 )
 
@@ -43,6 +47,7 @@ func defaultFileTestOpts(filename string) map[string]interface{} {
 		"EnableCache":     false,
 		"EnableLog":       false,
 		"CheckDoctype":    false,
+		"RetainAllIssues": true,
 	}
 }
 
@@ -71,6 +76,7 @@ func defaultDirectoryTestOpts(filename string) map[string]interface{} {
 		"EnableCache":     false,
 		"EnableLog":       false,
 		"CheckDoctype":    false,
+		"RetainAllIssues": true,
 	}
 }
 
@@ -90,6 +96,32 @@ func tTestDirectoryOpts(filename string, tOpts map[string]interface{}) *HTMLTest
 	return hT
 }
 
+// Test a temporary document linking to the given paths on baseURL, for tests
+// against a local server.
+func tTestExternalLinks(baseURL string, paths []string, tOpts map[string]interface{}) *HTMLTest {
+	dir, err := ioutil.TempDir("", "htmltest-external")
+	output.CheckErrorPanic(err)
+	defer os.RemoveAll(dir)
+
+	links := ""
+	for _, p := range paths {
+		links += fmt.Sprintf("<a href=\"%s%s\">link</a>\n", baseURL, p)
+	}
+	err = ioutil.WriteFile(path.Join(dir, "index.html"), []byte("<html><body>"+links+"</body></html>"), 0644)
+	output.CheckErrorPanic(err)
+
+	return tTestFileOpts(path.Join(dir, "index.html"), tOpts)
+}
+
+// Paths /0 to /n-1
+func tPaths(n int) []string {
+	paths := make([]string, n)
+	for i := range paths {
+		paths[i] = "/" + strconv.Itoa(i)
+	}
+	return paths
+}
+
 // All tests that make network calls should be marked with this function
 func tSkipShortExternal(t *testing.T) {
 	if testing.Short() {
@@ -5,6 +5,11 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strings"
+
+	"github.com/wjdp/htmltest/htmldoc"
+	"github.com/wjdp/htmltest/issues"
+	"golang.org/x/net/html"
 )
 
 type CertChainErr struct {
@@ -46,10 +51,12 @@ func validateCertChain(cert *x509.Certificate) (err error) {
 		}
 
 		if resp.StatusCode != 200 {
+			resp.Body.Close()
 			return CertChainErr{cert: cert, chain: intermediates, hintErr: fmt.Errorf("could not fetch certificate at %s (status %d)", url, resp.StatusCode)}
 		}
 
 		certBytes, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
 		if err != nil {
 			return CertChainErr{cert: cert, chain: intermediates, hintErr: err}
 		}
@@ -76,3 +83,31 @@ func validateCertChain(cert *x509.Certificate) (err error) {
 	})
 	return
 }
+
+// Does the space separated rel attribute value contain the given token?
+func relContains(rel string, token string) bool {
+	for _, t := range strings.Fields(strings.ToLower(rel)) {
+		if t == token {
+			return true
+		}
+	}
+	return false
+}
+
+// Add an issue raised by check referencing urlStr on node, or just the
+// document if there's no usable URL.
+func (hT *HTMLTest) addNodeIssue(check string, level int, document *htmldoc.Document, node *html.Node,
+	urlStr string, message string) {
+	var ref *htmldoc.Reference
+	if urlStr != "" {
+		// Bad references are reported by the node's own check
+		ref, _ = htmldoc.NewReference(document, node, urlStr)
+	}
+	hT.issueStore.AddIssue(issues.Issue{
+		Level:     level,
+		Document:  document,
+		Message:   message,
+		Reference: ref,
+		Check:     check,
+	})
+}
@@ -0,0 +1,287 @@
+package htmltest
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wjdp/htmltest/htmldoc"
+	"github.com/wjdp/htmltest/issues"
+	"github.com/wjdp/htmltest/refcache"
+	"golang.org/x/net/html"
+)
+
+// Diagnosis : Everything htmltest sees when checking a single external URL,
+// see Why.
+type Diagnosis struct {
+	URL            string              // URL as checked, after StripQueryString
+	Ignored        bool                // Matches IgnoreURLs, so never checked
+	Cached         *refcache.CachedRef // Cache entry, nil if there's none
+	CacheExpired   bool                // Cached is too old to be used
+	HostProfile    string              // Header profile remembered for the host
+	Proxy          string              // Proxy requests go through, empty if none
+	RequestHeader  http.Header
+	Hops           []DiagnosisHop // Redirects followed, in order
+	StatusCode     int            // Of the final response, zero on error
+	ResponseHeader http.Header
+	Timing         DiagnosisTiming
+	Certificates   []*x509.Certificate // Presented by the final host, leaf first
+	Err            error               // Client error, if any
+	Issues         []issues.Issue      // Warnings and errors a run would raise
+}
+
+// DiagnosisHop : A redirect followed while diagnosing.
+type DiagnosisHop struct {
+	URL        string
+	StatusCode int
+	Location   string
+}
+
+// DiagnosisTiming : Time spent in each phase of the request, summed over
+// redirects. Phases skipped through connection reuse are zero.
+type DiagnosisTiming struct {
+	DNS       time.Duration
+	Connect   time.Duration
+	TLS       time.Duration
+	FirstByte time.Duration // From sending the first request to the final response starting
+	Total     time.Duration
+}
+
+// Why : Diagnose a single external URL with the client, headers, cache and
+// checks the given options configure. The cache is read but not written.
+func Why(optsUser map[string]interface{}, urlStr string) (*Diagnosis, error) {
+	u, err := url.Parse(urlStr)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, errors.New("Why needs an absolute http or https URL, got '" + urlStr + "'")
+	}
+
+	optsRun := make(map[string]interface{}, len(optsUser)+1)
+	for key, value := range optsUser {
+		optsRun[key] = value
+	}
+	optsRun["NoRun"] = true
+	optsRun["OnlyFailed"] = false
+	hT, err := Test(optsRun)
+	if err != nil {
+		return nil, err
+	}
+	hT.opts.CheckExternal = true
+	return hT.why(urlStr), nil
+}
+
+func (hT *HTMLTest) why(urlStr string) *Diagnosis {
+	d := &Diagnosis{URL: hT.externalURL(urlStr)}
+	if hT.opts.isURLIgnored(d.URL) {
+		d.Ignored = true
+		return d
+	}
+
+	// Look before checking, which may update the cache
+	if cR, ok := hT.refCache.Peek(d.URL); ok {
+		_, fresh := hT.refCache.Get(d.URL)
+		d.Cached, d.CacheExpired = cR, !fresh
+	}
+	u, _ := url.Parse(d.URL)
+	d.HostProfile = hT.refCache.GetHostProfile(u.Host)
+
+	// A cache of our own so checkExternal makes the request rather than use a
+	// cached status, the result then comes from the response shown
+	hT.refCache = refcache.NewRefCache("", hT.opts.CacheExpires)
+	if d.HostProfile != "" {
+		hT.refCache.SaveHostProfile(u.Host, d.HostProfile)
+	}
+	hT.traceExternal = hT.whyTrace(d)
+
+	// Collect what a run would raise, on a store of our own
+	var mutex sync.Mutex
+	hT.issueStore = issues.NewIssueStore(issues.LevelNone, false)
+	hT.issueStore.SetIssueHook(func(issue issues.Issue) {
+		if issue.Level >= issues.LevelWarning {
+			mutex.Lock()
+			d.Issues = append(d.Issues, issue)
+			mutex.Unlock()
+		}
+	})
+	document := &htmldoc.Document{SitePath: "why"}
+	document.Init()
+	node := &html.Node{Type: html.ElementNode, Data: "a",
+		Attr: []html.Attribute{{Key: "href", Val: urlStr}}}
+	ref, err := htmldoc.NewReference(document, node, urlStr)
+	if err == nil {
+		hT.enforceHTTPS(ref)
+		hT.checkExternal(ref)
+		// Add a connection failure held back for the host's health
+		hT.checkHosts()
+	}
+	return d
+}
+
+// Trace the requests checkExternal makes, filling in d. A retry, such as
+// one as a browser when blocked for bots, replaces what was filled in.
+func (hT *HTMLTest) whyTrace(d *Diagnosis) func(*http.Request) (*http.Response, error) {
+	return func(req *http.Request) (*http.Response, error) {
+		d.Hops, d.StatusCode, d.ResponseHeader, d.Certificates, d.Err = nil, 0, nil, nil, nil
+		d.Timing = DiagnosisTiming{}
+		resp, err := hT.whyRequest(d, req)
+		d.Err = err
+		return resp, err
+	}
+}
+
+// Make a traced request, filling in d.
+func (hT *HTMLTest) whyRequest(d *Diagnosis, req *http.Request) (*http.Response, error) {
+	d.RequestHeader = req.Header
+
+	client := *hT.httpClient
+	if hT.httpTransport.Proxy != nil {
+		if proxy, err := hT.httpTransport.Proxy(req); err == nil && proxy != nil {
+			d.Proxy = proxy.String()
+		}
+	}
+	client.CheckRedirect = func(next *http.Request, via []*http.Request) error {
+		d.Hops = append(d.Hops, DiagnosisHop{
+			URL:        via[len(via)-1].URL.String(),
+			StatusCode: next.Response.StatusCode,
+			Location:   next.URL.String(),
+		})
+		if hT.httpClient.CheckRedirect != nil {
+			return hT.httpClient.CheckRedirect(next, via)
+		}
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+
+	var dnsStart, connectStart, tlsStart time.Time
+	// Dialling may race IPv4 and IPv6 connections
+	var connectMutex sync.Mutex
+	start := time.Now()
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { dnsStart = time.Now() },
+		DNSDone:  func(httptrace.DNSDoneInfo) { d.Timing.DNS += time.Since(dnsStart) },
+		ConnectStart: func(string, string) {
+			connectMutex.Lock()
+			connectStart = time.Now()
+			connectMutex.Unlock()
+		},
+		ConnectDone: func(string, string, error) {
+			connectMutex.Lock()
+			d.Timing.Connect += time.Since(connectStart)
+			connectMutex.Unlock()
+		},
+		TLSHandshakeStart: func() { tlsStart = time.Now() },
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			d.Timing.TLS += time.Since(tlsStart)
+			d.Certificates = state.PeerCertificates
+		},
+		GotFirstResponseByte: func() { d.Timing.FirstByte = time.Since(start) },
+	}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+
+	resp, err := client.Do(req)
+	d.Timing.Total = time.Since(start)
+	if err != nil {
+		// Verification failures only leave the leaf to show
+		var certErr x509.UnknownAuthorityError
+		if errors.As(err, &certErr) && len(d.Certificates) == 0 && certErr.Cert != nil {
+			d.Certificates = []*x509.Certificate{certErr.Cert}
+		}
+		return resp, err
+	}
+	d.StatusCode = resp.StatusCode
+	d.ResponseHeader = resp.Header
+	if resp.TLS != nil {
+		d.Certificates = resp.TLS.PeerCertificates
+	}
+	return resp, nil
+}
+
+// Write : Print the diagnosis for people to read.
+func (d *Diagnosis) Write(w io.Writer) {
+	fmt.Fprintln(w, "URL:", d.URL)
+	if d.Ignored {
+		fmt.Fprintln(w, "ignored, matches IgnoreURLs")
+		return
+	}
+
+	switch {
+	case d.Cached == nil:
+		fmt.Fprintln(w, "Cache: no entry")
+	case d.CacheExpired:
+		fmt.Fprintf(w, "Cache: status %d, seen %s, expired\n", d.Cached.StatusCode, d.Cached.LastSeen.Format(time.RFC3339))
+	default:
+		fmt.Fprintf(w, "Cache: status %d, seen %s\n", d.Cached.StatusCode, d.Cached.LastSeen.Format(time.RFC3339))
+	}
+	if d.HostProfile != "" {
+		fmt.Fprintln(w, "Host profile:", d.HostProfile)
+	}
+	if d.Proxy != "" {
+		fmt.Fprintln(w, "Proxy:", d.Proxy)
+	} else {
+		fmt.Fprintln(w, "Proxy: none")
+	}
+
+	fmt.Fprintln(w, "\nRequest headers:")
+	writeHeader(w, d.RequestHeader)
+
+	if len(d.Hops) > 0 {
+		fmt.Fprintln(w, "\nRedirects:")
+		for _, hop := range d.Hops {
+			fmt.Fprintf(w, "  %d %s -> %s\n", hop.StatusCode, hop.URL, hop.Location)
+		}
+	}
+
+	if d.Err != nil {
+		fmt.Fprintln(w, "\nRequest failed:", d.Err)
+	} else {
+		fmt.Fprintf(w, "\nResponse: %d %s\n", d.StatusCode, http.StatusText(d.StatusCode))
+		writeHeader(w, d.ResponseHeader)
+	}
+
+	fmt.Fprintf(w, "\nTiming: DNS %s, connect %s, TLS %s, first byte %s, total %s\n",
+		d.Timing.DNS.Round(time.Millisecond), d.Timing.Connect.Round(time.Millisecond),
+		d.Timing.TLS.Round(time.Millisecond), d.Timing.FirstByte.Round(time.Millisecond),
+		d.Timing.Total.Round(time.Millisecond))
+
+	if len(d.Certificates) > 0 {
+		fmt.Fprintln(w, "\nCertificates:")
+		for i, cert := range d.Certificates {
+			fmt.Fprintf(w, "  %d: %s\n     issued by %s, valid %s to %s\n", i, cert.Subject, cert.Issuer,
+				cert.NotBefore.Format("2006-01-02"), cert.NotAfter.Format("2006-01-02"))
+		}
+	}
+
+	fmt.Fprintln(w)
+	if len(d.Issues) == 0 {
+		fmt.Fprintln(w, "Result: passes")
+	}
+	for _, issue := range d.Issues {
+		line := fmt.Sprintf("Result: %s: %s", issues.LevelName(issue.Level), issue.Message)
+		if issue.Suggestion != "" {
+			line += " (suggest " + issue.Suggestion + ")"
+		}
+		fmt.Fprintln(w, line)
+	}
+}
+
+// Write headers sorted by name, one per line.
+func writeHeader(w io.Writer, header http.Header) {
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(w, "  %s: %s\n", key, strings.Join(header[key], ", "))
+	}
+}
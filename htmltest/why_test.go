@@ -0,0 +1,140 @@
+package htmltest
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/daviddengcn/go-assert"
+)
+
+// Server with a redirect, a page and a missing page
+func tWhyServer(tls bool) *httptest.Server {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/start":
+			http.Redirect(w, r, "/page", http.StatusMovedPermanently)
+		case "/page":
+			w.Header().Set("X-Served-By", "test")
+			w.Write([]byte("hello"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	server := httptest.NewUnstartedServer(handler)
+	// Rejected certificates are expected, keep quiet about them
+	server.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	if tls {
+		server.StartTLS()
+	} else {
+		server.Start()
+	}
+	return server
+}
+
+func tWhyOpts() map[string]interface{} {
+	return map[string]interface{}{
+		"LogLevel":      tLogLevel,
+		"EnableCache":   false,
+		"EnableLog":     false,
+		"CheckExternal": false, // Ignored, the URL is checked regardless
+	}
+}
+
+func TestWhyRedirects(t *testing.T) {
+	server := tWhyServer(false)
+	defer server.Close()
+
+	d, err := Why(tWhyOpts(), server.URL+"/start?utm=x")
+	assert.Equals(t, "error", err, nil)
+	assert.Equals(t, "url", d.URL, server.URL+"/start")
+	assert.Equals(t, "hops", len(d.Hops), 1)
+	assert.Equals(t, "hop status", d.Hops[0].StatusCode, http.StatusMovedPermanently)
+	assert.Equals(t, "hop location", d.Hops[0].Location, server.URL+"/page")
+	assert.Equals(t, "status", d.StatusCode, http.StatusOK)
+	assert.Equals(t, "response header", d.ResponseHeader.Get("X-Served-By"), "test")
+	assert.Equals(t, "request header", d.RequestHeader.Get("User-Agent"), "htmltest/dev")
+	assert.IsTrue(t, "first byte", d.Timing.FirstByte > 0)
+	assert.Equals(t, "issues", len(d.Issues), 0)
+
+	var out bytes.Buffer
+	d.Write(&out)
+	for _, want := range []string{"Cache: no entry", "301 " + server.URL + "/start -> " + server.URL + "/page",
+		"Response: 200 OK", "X-Served-By: test", "Timing: DNS", "Result: passes"} {
+		assert.IsTrue(t, want, strings.Contains(out.String(), want))
+	}
+}
+
+func TestWhyBroken(t *testing.T) {
+	// the issue a run would raise is included
+	server := tWhyServer(false)
+	defer server.Close()
+
+	d, err := Why(tWhyOpts(), server.URL+"/gone")
+	assert.Equals(t, "error", err, nil)
+	assert.Equals(t, "status", d.StatusCode, http.StatusNotFound)
+	assert.Equals(t, "issues", len(d.Issues), 1)
+	assert.Equals(t, "message", d.Issues[0].Message, "Non-OK status: 404")
+
+	var out bytes.Buffer
+	d.Write(&out)
+	assert.IsTrue(t, "result", strings.Contains(out.String(), "Result: error: Non-OK status: 404"))
+}
+
+func TestWhyCertificates(t *testing.T) {
+	server := tWhyServer(true)
+	defer server.Close()
+
+	// the test server's certificate isn't trusted
+	d, err := Why(tWhyOpts(), server.URL+"/page")
+	assert.Equals(t, "error", err, nil)
+	assert.NotEquals(t, "request error", d.Err, nil)
+	assert.Equals(t, "certificates", len(d.Certificates), 1)
+	assert.Equals(t, "issues", len(d.Issues), 1)
+
+	opts := tWhyOpts()
+	opts["IgnoreSSLVerify"] = true
+	d, err = Why(opts, server.URL+"/page")
+	assert.Equals(t, "error", err, nil)
+	assert.Equals(t, "status", d.StatusCode, http.StatusOK)
+	assert.Equals(t, "certificates", len(d.Certificates), 1)
+	assert.IsTrue(t, "tls timing", d.Timing.TLS > 0)
+}
+
+func TestWhyIgnored(t *testing.T) {
+	opts := tWhyOpts()
+	opts["IgnoreURLs"] = []interface{}{"example.com"}
+	d, err := Why(opts, "https://example.com/")
+	assert.Equals(t, "error", err, nil)
+	assert.IsTrue(t, "ignored", d.Ignored)
+}
+
+func TestWhyBadURL(t *testing.T) {
+	_, err := Why(tWhyOpts(), "/relative")
+	assert.NotEquals(t, "error", err, nil)
+}
+
+func TestWhySingleRequest(t *testing.T) {
+	// the response shown is the one the result comes from
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	d, err := Why(tWhyOpts(), server.URL+"/flaky")
+	assert.Equals(t, "error", err, nil)
+	assert.Equals(t, "requests", requests, 1)
+	assert.Equals(t, "status", d.StatusCode, http.StatusServiceUnavailable)
+	assert.Equals(t, "issues", len(d.Issues), 1)
+	assert.Equals(t, "issue", d.Issues[0].Message, "Non-OK status: 503")
+}
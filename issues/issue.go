@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/fatih/color"
 	"github.com/wjdp/htmltest/htmldoc"
+	"time"
 )
 
 const (
@@ -24,11 +25,14 @@ const (
 // Issue struct representing a single issue with a document.
 // Set all except Document and Reference, set one or the other.
 type Issue struct {
-	Level     int                // Level of the issue, use the consts at the top of this file
-	Document  *htmldoc.Document  // Document this issue pertains to
-	Reference *htmldoc.Reference // Reference this issue pertains to
-	Message   string             // Error message, keep short
-	store     *IssueStore        // Internal ref to the store this issue is owned by
+	Level      int                // Level of the issue, use the consts at the top of this file
+	Document   *htmldoc.Document  // Document this issue pertains to
+	Reference  *htmldoc.Reference // Reference this issue pertains to
+	Message    string             // Error message, keep short
+	Suggestion string             // Optional replacement for the reference, such as an archived copy
+	Check      string             // Name of the check raising the issue, see checkName
+	store      *IssueStore        // Internal ref to the store this issue is owned by
+	time       time.Time          // When the issue was added to the store
 }
 
 // Textual description of the primary item in the issue
@@ -43,7 +47,9 @@ func (issue *Issue) primary() string {
 
 // Textual description of the secondary item in the issue
 func (issue *Issue) secondary() string {
-	if issue.Reference != nil {
+	if issue.Reference != nil && issue.Reference.Field != "" {
+		return issue.Reference.Path + " at " + issue.Reference.Field
+	} else if issue.Reference != nil {
 		return issue.Reference.Path
 	}
 	return textNil
@@ -53,11 +59,15 @@ func (issue *Issue) secondary() string {
 func (issue *Issue) text() string {
 	pri := issue.primary()
 	sec := issue.secondary()
+	text := issue.Message
 	if pri != textNil || sec != textNil {
-		return fmt.Sprintf("%v --- %v --> %v", issue.Message, issue.primary(),
+		text = fmt.Sprintf("%v --- %v --> %v", issue.Message, issue.primary(),
 			issue.secondary())
 	}
-	return issue.Message
+	if issue.Suggestion != "" {
+		text += " (suggest " + issue.Suggestion + ")"
+	}
+	return text
 }
 
 // Print to stdout with optional colour (controlled by color.NoColor - see main())
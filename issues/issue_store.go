@@ -5,60 +5,107 @@ import (
 	"fmt"
 	"github.com/fatih/color"
 	"github.com/wjdp/htmltest/htmldoc"
-	"github.com/wjdp/htmltest/output"
-	"io/ioutil"
 	"strings"
 	"sync"
+	"time"
 )
 
-// IssueStore : store of htmltest issues.
+// IssueStore : store of htmltest issues. Only issues at or above retainLevel
+// are kept, the rest are counted and dropped.
 type IssueStore struct {
-	logLevel         int                 // Level of errors to report
-	printImmediately bool                // Print issues when added
-	issues           []*Issue            // All issues
-	issuesByDoc      map[string][]*Issue // Issues by Document.SitePath
-	storeMutex       *sync.RWMutex       // Mutex to control access to stores
-	byteLog          []byte              // Bytestream of issues, built when issues are added and written to disk at end
+	logLevel         int                    // Level of errors to report
+	printImmediately bool                   // Print issues when added
+	retainLevel      int                    // Lowest level the console or a sink needs
+	issues           []*Issue               // Retained issues not yet spilled
+	issuesByDoc      map[string][]*Issue    // Retained issues by Document.SitePath
+	counts           map[issueKey]int       // Every issue added, by level and check
+	countsByDoc      map[string]map[int]int // Every issue added, by primary and level
+	printed          map[string]bool        // Primaries PrintDocumentIssues has been called for
+	spill            *issueSpill            // Where issues go beyond a threshold, nil if disabled
+	storeMutex       *sync.RWMutex          // Mutex to control access to stores
+	sinks            []Sink                 // Files written at the end of the run
+	issueHook        func(Issue)            // Called for every issue added
+}
+
+// issueKey : what issues are counted by, see checkName
+type issueKey struct {
+	level int
+	check string
 }
 
 // NewIssueStore : Create an issuestore, assigns defaults and returns.
 func NewIssueStore(logLevel int, printImmediately bool) IssueStore {
 	iS := IssueStore{logLevel: logLevel, printImmediately: printImmediately}
+	iS.retainLevel = logLevel
 	iS.issues = make([]*Issue, 0)
 	iS.issuesByDoc = make(map[string][]*Issue)
+	iS.counts = make(map[issueKey]int)
+	iS.countsByDoc = make(map[string]map[int]int)
+	iS.printed = make(map[string]bool)
 	iS.storeMutex = &sync.RWMutex{}
-	iS.byteLog = make([]byte, 0)
+	iS.sinks = make([]Sink, 0)
 	return iS
 }
 
 // AddIssue : Add an issue to the issue store, thread safe.
 func (iS *IssueStore) AddIssue(issue Issue) {
 	issue.store = iS // Set ref to issue store in issue
+	issue.time = time.Now()
 
 	iS.storeMutex.Lock()
 
-	iS.issues = append(iS.issues, &issue)
-	iS.issuesByDoc[issue.primary()] = append(
-		iS.issuesByDoc[issue.primary()], &issue)
+	primary := issue.primary()
+	iS.counts[issueKey{issue.Level, issue.checkName()}]++
+	countLevel(iS.countsByDoc, primary, issue.Level)
+
+	if issue.Level >= iS.retainLevel {
+		iS.issues = append(iS.issues, &issue)
+		iS.issuesByDoc[primary] = append(iS.issuesByDoc[primary], &issue)
+		if iS.spill != nil && len(iS.issues) > iS.spill.next {
+			iS.spillIssues()
+		}
+	}
 
-	if iS.printImmediately || issue.primary() == textNil {
+	// Issues arriving after their document was printed, such as held back
+	// external failures, are printed as they come
+	if iS.printImmediately || primary == textNil || iS.printed[primary] {
 		issue.print(false, "")
 	}
-	if issue.Level >= iS.logLevel {
-		// Build byte slice to write out at the end
-		iS.byteLog = append(iS.byteLog, []byte(issue.text()+"\n")...)
+
+	hook := iS.issueHook
+	iS.storeMutex.Unlock()
+
+	// Outside the lock so the hook may query the store
+	if hook != nil {
+		hook(issue)
+	}
+}
+
+// Increment counts[key][level].
+func countLevel(counts map[string]map[int]int, key string, level int) {
+	if counts[key] == nil {
+		counts[key] = make(map[int]int)
 	}
+	counts[key][level]++
+}
 
+// SetIssueHook : Call fn with every issue added from now on, nil to remove.
+// fn must be safe to call from multiple goroutines.
+func (iS *IssueStore) SetIssueHook(fn func(Issue)) {
+	iS.storeMutex.Lock()
+	iS.issueHook = fn
 	iS.storeMutex.Unlock()
 }
 
 // Count : Counts the number of issues in the store at, or above, the given
-// level.
+// level. Includes issues which weren't retained.
 func (iS *IssueStore) Count(level int) int {
+	iS.storeMutex.RLock()
+	defer iS.storeMutex.RUnlock()
 	count := 0
-	for _, issue := range iS.issues {
-		if issue.Level >= level {
-			count++
+	for key, n := range iS.counts {
+		if key.level >= level {
+			count += n
 		}
 	}
 	return count
@@ -68,31 +115,43 @@ func (iS *IssueStore) Count(level int) int {
 // level pertaining to the provided document. Thread safe.
 func (iS *IssueStore) CountByDoc(level int, doc *htmldoc.Document) int {
 	iS.storeMutex.RLock()
+	defer iS.storeMutex.RUnlock()
+	return sumLevels(iS.countsByDoc[doc.SitePath], level)
+}
+
+// Sum the counts at or above level.
+func sumLevels(counts map[int]int, level int) int {
 	count := 0
-	for _, issue := range iS.issuesByDoc[doc.SitePath] {
-		if issue.Level >= level {
-			count++
+	for l, n := range counts {
+		if l >= level {
+			count += n
 		}
 	}
-	iS.storeMutex.RUnlock()
 	return count
 }
 
-// MessageMatchCount : Count the number of issues in the store containing the
-// provided substr.
+// MessageMatchCount : Count the number of retained issues whose message
+// contains the provided substr, see RetainLevel.
 func (iS *IssueStore) MessageMatchCount(substr string) int {
+	iS.storeMutex.RLock()
+	defer iS.storeMutex.RUnlock()
 	count := 0
-	for _, issue := range iS.issues {
+	iS.eachIssue(func(issue *Issue) {
 		if strings.Contains(issue.Message, substr) {
 			count++
 		}
-	}
+	})
 	return count
 }
 
 // PrintDocumentIssues : Print issues pertaining to a single document, given
 // that document's SitePath. Respects log level.
 func (iS *IssueStore) PrintDocumentIssues(doc *htmldoc.Document) {
+	iS.storeMutex.Lock()
+	// Nothing more is expected for the document, its issues may be spilled
+	iS.printed[doc.SitePath] = true
+	iS.storeMutex.Unlock()
+
 	if iS.CountByDoc(iS.logLevel, doc) == 0 {
 		if iS.logLevel == LevelDebug {
 			color.Set(color.FgMagenta)
@@ -109,19 +168,31 @@ func (iS *IssueStore) PrintDocumentIssues(doc *htmldoc.Document) {
 	iS.storeMutex.RUnlock()
 }
 
+// Call fn with every retained issue, spilled ones first as they're read back,
+// then those in memory. Caller holds storeMutex.
+func (iS *IssueStore) eachIssue(fn func(*Issue)) {
+	if iS.spill != nil && iS.spill.count > 0 {
+		iS.spill.read(iS, fn)
+	}
+	for _, issue := range iS.issues {
+		fn(issue)
+	}
+}
+
 // WriteLog : Write the issue store to the given path, filtered by logLevel
-// given in NewIssueStore.
+// given in NewIssueStore. Shorthand for a text Sink without run metadata.
 func (iS *IssueStore) WriteLog(path string) {
-	err := ioutil.WriteFile(path, iS.byteLog, 0644)
-	output.CheckErrorPanic(err)
+	iS.WriteSink(Sink{Path: path, Level: iS.logLevel, Format: FormatText}, RunMeta{})
 }
 
-// DumpIssues : Dump all issues to stdout, called by test helpers when issue
-// asserts fail.
+// DumpIssues : Dump all retained issues to stdout, called by test helpers
+// when issue asserts fail.
 func (iS *IssueStore) DumpIssues(force bool) {
+	iS.storeMutex.RLock()
+	defer iS.storeMutex.RUnlock()
 	fmt.Println("<<<<<<<<<<<<<<<<<<<<<<<<")
-	for _, issue := range iS.issues {
+	iS.eachIssue(func(issue *Issue) {
 		issue.print(force, "")
-	}
+	})
 	fmt.Println(">>>>>>>>>>>>>>>>>>>>>>>>")
 }
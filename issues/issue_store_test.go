@@ -3,8 +3,10 @@ package issues
 import (
 	"github.com/daviddengcn/go-assert"
 	"github.com/wjdp/htmltest/htmldoc"
+	"golang.org/x/net/html"
 	"io/ioutil"
 	"os"
+	"path"
 	"strings"
 	"testing"
 )
@@ -33,6 +35,7 @@ func TestIssueStoreCount(t *testing.T) {
 
 func TestIssueStoreMessageMatchCount(t *testing.T) {
 	iS := NewIssueStore(LevelNone, false)
+	iS.RetainLevel(LevelDebug)
 	iS.AddIssue(Issue{Level: LevelError, Message: "error one"})
 	iS.AddIssue(Issue{Level: LevelWarning, Message: "error two"})
 	iS.AddIssue(Issue{Level: LevelInfo, Message: "notice"})
@@ -46,6 +49,63 @@ func TestIssueStoreMessageMatchCount(t *testing.T) {
 		iS.MessageMatchCount("notice"), 1)
 }
 
+func TestIssueStoreRetention(t *testing.T) {
+	iS := NewIssueStore(LevelError, false)
+	iS.AddSink(Sink{Path: "unused.json", Level: LevelWarning, Format: FormatJSON})
+	doc := htmldoc.Document{SitePath: "dir/page.html"}
+	for i := 0; i < 100; i++ {
+		iS.AddIssue(Issue{Level: LevelDebug, Message: "from cache", Document: &doc})
+	}
+	iS.AddIssue(Issue{Level: LevelWarning, Message: "warn", Document: &doc})
+	iS.AddIssue(Issue{Level: LevelError, Message: "error", Document: &doc})
+
+	assert.Equals(t, "retained", len(iS.issues), 2)
+	assert.Equals(t, "retained by doc", len(iS.issuesByDoc["dir/page.html"]), 2)
+	assert.Equals(t, "count all", iS.Count(LevelDebug), 102)
+	assert.Equals(t, "count errors", iS.Count(LevelError), 1)
+	assert.Equals(t, "count by doc", iS.CountByDoc(LevelDebug, &doc), 102)
+	// Messages are only matched among retained issues
+	assert.Equals(t, "message count", iS.MessageMatchCount("cache"), 0)
+	assert.Equals(t, "message count", iS.MessageMatchCount("warn"), 1)
+}
+
+func TestIssueStoreSpill(t *testing.T) {
+	dir, err := ioutil.TempDir("", "htmltest-spill")
+	assert.Equals(t, "tempdir error", err, nil)
+	defer os.RemoveAll(dir)
+
+	iS := NewIssueStore(LevelError, false)
+	assert.Equals(t, "spill error", iS.SetSpill(path.Join(dir, "issues.spill"), 2), nil)
+	a := htmldoc.Document{SitePath: "a.html"}
+	b := htmldoc.Document{SitePath: "b.html"}
+	iS.AddIssue(Issue{Level: LevelError, Message: "first", Document: &a})
+	iS.AddIssue(Issue{Level: LevelError, Message: "site"})
+	iS.PrintDocumentIssues(&a)
+	// Over the threshold, a.html's and the site issue go, b.html's stays
+	iS.AddIssue(Issue{Level: LevelError, Message: "second",
+		Reference: &htmldoc.Reference{Document: &b, Path: "gpl.png", Node: &html.Node{Data: "img"}}})
+	assert.Equals(t, "in memory", len(iS.issues), 1)
+	assert.Equals(t, "spilled", iS.spill.count, 2)
+	assert.Equals(t, "count", iS.Count(LevelError), 3)
+
+	all := make([]*Issue, 0)
+	iS.eachIssue(func(issue *Issue) { all = append(all, issue) })
+	assert.Equals(t, "all issues", len(all), 3)
+	assert.Equals(t, "spilled document", all[0].text(), "first --- a.html --> <nil>")
+	assert.Equals(t, "spilled site", all[1].text(), "site")
+	assert.Equals(t, "kept", all[2].text(), "second --- b.html --> gpl.png")
+
+	md := iS.Markdown(0)
+	assert.IsTrue(t, "markdown spilled", strings.Contains(md, "- **error** first"))
+	assert.IsTrue(t, "markdown summary", strings.Contains(md, "| Images | 1 | 0 |"))
+
+	// Gone once sinks are written
+	iS.WriteSinks(RunMeta{})
+	_, err = os.Stat(path.Join(dir, "issues.spill"))
+	assert.IsTrue(t, "spill removed", os.IsNotExist(err))
+	assert.Equals(t, "count after", iS.Count(LevelError), 3)
+}
+
 func TestIssueStoreWriteLog(t *testing.T) {
 	// passes for log written using LogLevel
 	LOGFILE := "issue-store-test.log"
@@ -82,8 +142,9 @@ func TestIssueStoreWriteLog(t *testing.T) {
 }
 
 func ExampleIssueStoreDumpIssues() {
-	// Passes for dumping all issues, ignoring LogLevel
+	// Passes for dumping all retained issues, ignoring LogLevel
 	iS := NewIssueStore(LevelNone, true)
+	iS.RetainLevel(LevelDebug)
 	issue1 := Issue{
 		Level:   LevelError,
 		Message: "test1",
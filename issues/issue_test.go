@@ -38,6 +38,29 @@ func TestIssueSecondary(t *testing.T) {
 		Reference: &ref,
 	}
 	assert.Equals(t, "issue1 secondary", issue1.secondary(), "http://example.com")
+
+	ref2 := htmldoc.Reference{
+		Path:  "http://example.com",
+		Field: "$.items[0].url",
+	}
+	issue2 := Issue{
+		Reference: &ref2,
+	}
+	assert.Equals(t, "issue2 secondary", issue2.secondary(), "http://example.com at $.items[0].url")
+}
+
+func TestIssueTextSuggestion(t *testing.T) {
+	ref := htmldoc.Reference{
+		Document: &htmldoc.Document{SitePath: "doc.html"},
+		Path:     "http://example.com/gone",
+	}
+	issue := Issue{
+		Reference:  &ref,
+		Message:    "Non-OK status: 404",
+		Suggestion: "http://web.archive.org/web/2020/http://example.com/gone",
+	}
+	assert.Equals(t, "issue text", issue.text(), "Non-OK status: 404 --- doc.html --> http://example.com/gone"+
+		" (suggest http://web.archive.org/web/2020/http://example.com/gone)")
 }
 
 func ExampleIssuePrintLogLevel() {
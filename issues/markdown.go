@@ -0,0 +1,205 @@
+package issues
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+)
+
+// Heading of the section holding issues that aren't tied to a document
+const markdownSiteSection string = "Site"
+
+// Names of checks, by the tag of the node an issue's reference came from
+var markdownCheckNames = map[string]string{
+	"a":        "Anchors",
+	"link":     "Links",
+	"img":      "Images",
+	"script":   "Scripts",
+	"meta":     "Meta",
+	"datafile": "Data files",
+	"pdf":      "PDFs",
+}
+
+// NodeCheckName : Name of the check for elements with the given tag, for
+// issues on them which have no reference to take it from.
+func NodeCheckName(tag string) string {
+	if name, ok := markdownCheckNames[tag]; ok {
+		return name
+	}
+	return "Generic"
+}
+
+// Name of the check an issue was raised by, for the summary table. Checks
+// of elements are named by the reference's tag unless they set Check.
+func (issue *Issue) checkName() string {
+	if issue.Check != "" {
+		return issue.Check
+	}
+	if issue.Reference == nil || issue.Reference.Node == nil {
+		return "Document"
+	}
+	return NodeCheckName(issue.Reference.Node.Data)
+}
+
+// Markdown : Render a compact Markdown report suitable for pull request
+// comments and job summaries. A summary table of errors and warnings by check
+// is followed by a collapsible section per document. Sections respect the
+// logLevel given in NewIssueStore. Output is truncated to roughly maxBytes,
+// zero disables truncation.
+func (iS *IssueStore) Markdown(maxBytes int) string {
+	return iS.markdown(iS.logLevel, maxBytes)
+}
+
+// Markdown report with sections filtered by level.
+func (iS *IssueStore) markdown(level int, maxBytes int) string {
+	iS.storeMutex.RLock()
+	defer iS.storeMutex.RUnlock()
+
+	type counts struct{ errors, warnings int }
+	byCheck := make(map[string]*counts)
+	total := counts{}
+	for key, n := range iS.counts {
+		if key.level != LevelError && key.level != LevelWarning {
+			continue
+		}
+		if byCheck[key.check] == nil {
+			byCheck[key.check] = &counts{}
+		}
+		if key.level == LevelError {
+			byCheck[key.check].errors += n
+			total.errors += n
+		} else {
+			byCheck[key.check].warnings += n
+			total.warnings += n
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("## htmltest results\n\n")
+	if len(byCheck) == 0 {
+		b.WriteString("No errors or warnings.\n")
+		return b.String()
+	}
+
+	checks := make([]string, 0, len(byCheck))
+	for check := range byCheck {
+		checks = append(checks, check)
+	}
+	sort.Strings(checks)
+	b.WriteString("| Check | Errors | Warnings |\n| :---- | -----: | -------: |\n")
+	for _, check := range checks {
+		fmt.Fprintf(&b, "| %s | %d | %d |\n", check, byCheck[check].errors, byCheck[check].warnings)
+	}
+	fmt.Fprintf(&b, "| **Total** | **%d** | **%d** |\n\n", total.errors, total.warnings)
+
+	// Documents with issues at or above level, site wide issues first
+	sections := make([]string, 0)
+	for primary, levels := range iS.countsByDoc {
+		if sumLevels(levels, level) > 0 {
+			sections = append(sections, primary)
+		}
+	}
+	sort.Slice(sections, func(i, j int) bool {
+		if sections[i] == textNil || sections[j] == textNil {
+			return sections[i] == textNil
+		}
+		return sections[i] < sections[j]
+	})
+	sectionIndex := make(map[string]int, len(sections))
+	for i, primary := range sections {
+		sectionIndex[primary] = i
+	}
+
+	// Render lines as issues are read. Sections can only grow, so once those
+	// up to one no longer fit in maxBytes it and those after are dropped.
+	lines := make([][]string, len(sections))
+	fits, used := len(sections), b.Len()
+	iS.eachIssue(func(issue *Issue) {
+		i, ok := sectionIndex[issue.primary()]
+		if issue.Level < level || !ok || i >= fits {
+			return
+		}
+		line := markdownLine(issue)
+		lines[i] = append(lines[i], line)
+		used += len(line) + 1
+		for maxBytes > 0 && fits > 0 && used > maxBytes-100 {
+			fits--
+			for _, dropped := range lines[fits] {
+				used -= len(dropped) + 1
+			}
+			lines[fits] = nil
+		}
+	})
+
+	for i, primary := range sections {
+		section := ""
+		if i < fits {
+			section = iS.markdownSection(primary, lines[i])
+		}
+		// Leave room for the truncation note
+		if i >= fits || (maxBytes > 0 && b.Len()+len(section) > maxBytes-100) {
+			fmt.Fprintf(&b, "_…and %d more documents with issues, output truncated._\n", len(sections)-i)
+			break
+		}
+		b.WriteString(section)
+	}
+	return b.String()
+}
+
+// Render the collapsible section for one primary from its issues' lines,
+// caller holds storeMutex.
+func (iS *IssueStore) markdownSection(primary string, lines []string) string {
+	counts := iS.countsByDoc[primary]
+	errors, warnings := counts[LevelError], counts[LevelWarning]
+	title := markdownSiteSection
+	if primary != textNil {
+		title = "<code>" + html.EscapeString(primary) + "</code>"
+	}
+	return fmt.Sprintf("<details><summary>%s — %d errors, %d warnings</summary>\n\n%s\n\n</details>\n\n",
+		title, errors, warnings, strings.Join(lines, "\n"))
+}
+
+// WriteMarkdown : Write the Markdown report to the given path. Shorthand for a
+// markdown Sink at logLevel.
+func (iS *IssueStore) WriteMarkdown(filePath string, maxBytes int) {
+	iS.WriteSink(Sink{Path: filePath, Level: iS.logLevel, Format: FormatMarkdown, MaxBytes: maxBytes}, RunMeta{})
+}
+
+// Render an issue as a list item.
+func markdownLine(issue *Issue) string {
+	line := "- " + markdownLevel(issue.Level) + " " + markdownEscape(issue.Message)
+	if sec := issue.secondary(); sec != textNil {
+		line += " → `" + strings.Replace(sec, "`", "'", -1) + "`"
+	}
+	if issue.Suggestion != "" {
+		line += ", suggest `" + strings.Replace(issue.Suggestion, "`", "'", -1) + "`"
+	}
+	return line
+}
+
+// Short bold label for a level.
+func markdownLevel(level int) string {
+	switch level {
+	case LevelError:
+		return "**error**"
+	case LevelWarning:
+		return "**warning**"
+	case LevelInfo:
+		return "info"
+	}
+	return "debug"
+}
+
+// Escape text so messages such as "<img> with usemap" render literally.
+func markdownEscape(s string) string {
+	return strings.NewReplacer(
+		"&", "&amp;",
+		"<", "&lt;",
+		">", "&gt;",
+		"|", "\\|",
+		"*", "\\*",
+		"_", "\\_",
+		"`", "\\`",
+	).Replace(s)
+}
@@ -0,0 +1,53 @@
+package issues
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/daviddengcn/go-assert"
+	"github.com/wjdp/htmltest/htmldoc"
+	"golang.org/x/net/html"
+)
+
+func TestIssueStoreMarkdown(t *testing.T) {
+	iS := NewIssueStore(LevelWarning, false)
+	doc := htmldoc.Document{SitePath: "dir/page.html"}
+	img := &html.Node{Type: html.ElementNode, Data: "img"}
+	iS.AddIssue(Issue{Level: LevelError, Message: "missing doctype", Document: &doc, Check: "Doctype"})
+	iS.AddIssue(Issue{Level: LevelError, Message: "<img> not allowed",
+		Reference: &htmldoc.Reference{Document: &doc, Node: img, Path: "gpl.png"}})
+	iS.AddIssue(Issue{Level: LevelWarning, Message: "running in concurrent mode"})
+	iS.AddIssue(Issue{Level: LevelDebug, Message: "hidden", Document: &doc})
+
+	md := iS.Markdown(0)
+	assert.IsTrue(t, "summary row", strings.Contains(md, "| Images | 1 | 0 |"))
+	assert.IsTrue(t, "summary row", strings.Contains(md, "| Doctype | 1 | 0 |"))
+	assert.IsTrue(t, "summary row", strings.Contains(md, "| Document | 0 | 1 |"))
+	assert.IsTrue(t, "summary total", strings.Contains(md, "| **Total** | **2** | **1** |"))
+	assert.IsTrue(t, "document section",
+		strings.Contains(md, "<summary><code>dir/page.html</code> — 2 errors, 0 warnings</summary>"))
+	assert.IsTrue(t, "escaped message",
+		strings.Contains(md, "- **error** &lt;img&gt; not allowed → `gpl.png`"))
+	assert.IsTrue(t, "site section first",
+		strings.Index(md, "<summary>Site") < strings.Index(md, "<summary><code>dir"))
+	assert.IsFalse(t, "below log level", strings.Contains(md, "hidden"))
+}
+
+func TestIssueStoreMarkdownEmpty(t *testing.T) {
+	iS := NewIssueStore(LevelWarning, false)
+	iS.AddIssue(Issue{Level: LevelInfo, Message: "notice"})
+	assert.Equals(t, "markdown", iS.Markdown(0), "## htmltest results\n\nNo errors or warnings.\n")
+}
+
+func TestIssueStoreMarkdownTruncate(t *testing.T) {
+	iS := NewIssueStore(LevelError, false)
+	for _, p := range []string{"a.html", "b.html", "c.html", "d.html"} {
+		doc := htmldoc.Document{SitePath: p}
+		iS.AddIssue(Issue{Level: LevelError, Message: strings.Repeat("x", 200), Document: &doc})
+	}
+	md := iS.Markdown(800)
+	assert.IsTrue(t, "truncated size", len(md) <= 800)
+	assert.IsTrue(t, "first section kept", strings.Contains(md, "a.html"))
+	assert.IsFalse(t, "last section dropped", strings.Contains(md, "d.html"))
+	assert.IsTrue(t, "truncation note", strings.Contains(md, "more documents with issues, output truncated"))
+}
@@ -0,0 +1,233 @@
+package issues
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wjdp/htmltest/output"
+)
+
+const (
+	// FormatText : One issue per line, prefixed with a timestamp and level
+	FormatText string = "text"
+	// FormatJSON : A single JSON document with run metadata and all issues
+	FormatJSON string = "json"
+	// FormatMarkdown : The Markdown report, see IssueStore.Markdown
+	FormatMarkdown string = "markdown"
+)
+
+// Sink : a file issues are written to at the end of a run, with its own
+// minimum level and format. Console output is controlled by the store's
+// logLevel.
+type Sink struct {
+	Path     string // Where to write
+	Level    int    // Minimum level of issues to include
+	Format   string // One of the Format consts
+	MaxBytes int    // Truncation size for FormatMarkdown, 0 to disable
+}
+
+// RunMeta : details of the run written alongside issues by file sinks.
+type RunMeta struct {
+	Version       string
+	DirectoryPath string
+	Started       time.Time
+	Finished      time.Time
+	Documents     int
+}
+
+// Names of levels as used in sinks and config, see LevelName and ParseLevel
+var levelNames = map[int]string{
+	LevelDebug:   "debug",
+	LevelInfo:    "info",
+	LevelWarning: "warning",
+	LevelError:   "error",
+	LevelNone:    "none",
+}
+
+// LevelName : Lowercase name of the given level.
+func LevelName(level int) string {
+	if name, ok := levelNames[level]; ok {
+		return name
+	}
+	return strconv.Itoa(level)
+}
+
+// ParseLevel : Parse a level given as an int or a name such as "warning".
+func ParseLevel(v interface{}) (int, error) {
+	switch l := v.(type) {
+	case int:
+		return l, nil
+	case string:
+		for level, name := range levelNames {
+			if strings.EqualFold(l, name) {
+				return level, nil
+			}
+		}
+		if level, err := strconv.Atoi(l); err == nil {
+			return level, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid level %v", v)
+}
+
+// ValidFormat : Is format one of the Format consts?
+func ValidFormat(format string) bool {
+	return format == FormatText || format == FormatJSON || format == FormatMarkdown
+}
+
+// AddSink : Register a sink to be written by WriteSinks. Add sinks before any
+// issues, the store only retains issues the console or a sink needs.
+func (iS *IssueStore) AddSink(sink Sink) {
+	iS.storeMutex.Lock()
+	iS.sinks = append(iS.sinks, sink)
+	if sink.Level < iS.retainLevel {
+		iS.retainLevel = sink.Level
+	}
+	iS.storeMutex.Unlock()
+}
+
+// RetainLevel : Also retain issues at or above level from now on, for callers
+// which render the store themselves such as DumpIssues.
+func (iS *IssueStore) RetainLevel(level int) {
+	iS.storeMutex.Lock()
+	if level < iS.retainLevel {
+		iS.retainLevel = level
+	}
+	iS.storeMutex.Unlock()
+}
+
+// WriteSinks : Write every registered sink, meta is included in text and JSON
+// output. The spill file, if any, is removed once they're written.
+func (iS *IssueStore) WriteSinks(meta RunMeta) {
+	for _, sink := range iS.sinks {
+		iS.WriteSink(sink, meta)
+	}
+	iS.storeMutex.Lock()
+	iS.closeSpill()
+	iS.storeMutex.Unlock()
+}
+
+// WriteSink : Write a single sink. Run metadata is omitted when meta.Started
+// is zero.
+func (iS *IssueStore) WriteSink(sink Sink, meta RunMeta) {
+	os.MkdirAll(path.Dir(sink.Path), 0777)
+	f, err := os.OpenFile(sink.Path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	output.CheckErrorPanic(err)
+	w := bufio.NewWriter(f)
+
+	switch sink.Format {
+	case FormatJSON:
+		iS.sinkJSON(w, sink.Level, meta)
+	case FormatMarkdown:
+		io.WriteString(w, iS.markdown(sink.Level, sink.MaxBytes))
+	default:
+		iS.sinkText(w, sink.Level, meta)
+	}
+
+	// Write errors stick, so are only checked here
+	output.CheckErrorPanic(w.Flush())
+	output.CheckErrorPanic(f.Close())
+}
+
+// Write issues at or above level to w as timestamped lines.
+func (iS *IssueStore) sinkText(w io.Writer, level int, meta RunMeta) {
+	iS.storeMutex.RLock()
+	defer iS.storeMutex.RUnlock()
+
+	if !meta.Started.IsZero() {
+		fmt.Fprintf(w, "# htmltest %s on %q\n", meta.Version, meta.DirectoryPath)
+		fmt.Fprintf(w, "# started %s, finished %s, %d documents\n",
+			meta.Started.Format(time.RFC3339), meta.Finished.Format(time.RFC3339), meta.Documents)
+	}
+	iS.eachIssue(func(issue *Issue) {
+		if issue.Level < level {
+			return
+		}
+		fmt.Fprintf(w, "%s %-7s %s\n", issue.time.Format(time.RFC3339Nano),
+			strings.ToUpper(LevelName(issue.Level)), issue.text())
+	})
+}
+
+// jsonIssue : a single issue in FormatJSON output
+type jsonIssue struct {
+	Time       time.Time `json:"time"`
+	Level      string    `json:"level"`
+	Message    string    `json:"message"`
+	Document   string    `json:"document,omitempty"`
+	Reference  string    `json:"reference,omitempty"`
+	Suggestion string    `json:"suggestion,omitempty"`
+	Check      string    `json:"check"`
+}
+
+// jsonReport : a FormatJSON output bar its issues, which follow as "issues"
+type jsonReport struct {
+	Version       string         `json:"version,omitempty"`
+	DirectoryPath string         `json:"directoryPath,omitempty"`
+	Started       *time.Time     `json:"started,omitempty"`
+	Finished      *time.Time     `json:"finished,omitempty"`
+	Documents     int            `json:"documents"`
+	Counts        map[string]int `json:"counts"`
+}
+
+// Write issues at or above level to w as a JSON report. Issues are encoded
+// one at a time as they're read, the report is never held whole.
+func (iS *IssueStore) sinkJSON(w io.Writer, level int, meta RunMeta) {
+	iS.storeMutex.RLock()
+	defer iS.storeMutex.RUnlock()
+
+	report := jsonReport{
+		Version:       meta.Version,
+		DirectoryPath: meta.DirectoryPath,
+		Documents:     meta.Documents,
+		Counts:        make(map[string]int),
+	}
+	if !meta.Started.IsZero() {
+		report.Started, report.Finished = &meta.Started, &meta.Finished
+	}
+	for key, n := range iS.counts {
+		if key.level >= level {
+			report.Counts[LevelName(key.level)] += n
+		}
+	}
+	head, err := json.MarshalIndent(report, "", "  ")
+	output.CheckErrorPanic(err)
+	// Reopen the object, dropping the closing "\n}", to add issues
+	w.Write(head[:len(head)-2])
+	io.WriteString(w, ",\n  \"issues\": [")
+
+	separator := "\n    "
+	iS.eachIssue(func(issue *Issue) {
+		if issue.Level < level {
+			return
+		}
+		ji := jsonIssue{
+			Time:       issue.time,
+			Level:      LevelName(issue.Level),
+			Message:    issue.Message,
+			Suggestion: issue.Suggestion,
+			Check:      issue.checkName(),
+		}
+		if pri := issue.primary(); pri != textNil {
+			ji.Document = pri
+		}
+		if sec := issue.secondary(); sec != textNil {
+			ji.Reference = sec
+		}
+		out, err := json.MarshalIndent(ji, "    ", "  ")
+		output.CheckErrorPanic(err)
+		io.WriteString(w, separator)
+		w.Write(out)
+		separator = ",\n    "
+	})
+	if separator != "\n    " {
+		io.WriteString(w, "\n  ")
+	}
+	io.WriteString(w, "]\n}\n")
+}
@@ -0,0 +1,70 @@
+package issues
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daviddengcn/go-assert"
+	"github.com/wjdp/htmltest/htmldoc"
+)
+
+func TestParseLevel(t *testing.T) {
+	for _, v := range []interface{}{3, "3", "error", "ERROR"} {
+		level, err := ParseLevel(v)
+		assert.Equals(t, "parse error", err, nil)
+		assert.Equals(t, "level", level, LevelError)
+	}
+	_, err := ParseLevel("loud")
+	assert.NotEquals(t, "parse error", err, nil)
+}
+
+func TestIssueStoreWriteSinks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "htmltest-sinks")
+	assert.Equals(t, "tempdir error", err, nil)
+	defer os.RemoveAll(dir)
+
+	iS := NewIssueStore(LevelNone, false)
+	iS.AddSink(Sink{Path: path.Join(dir, "debug.log"), Level: LevelDebug, Format: FormatText})
+	iS.AddSink(Sink{Path: path.Join(dir, "report.json"), Level: LevelWarning, Format: FormatJSON})
+	doc := htmldoc.Document{SitePath: "dir/page.html"}
+	iS.AddIssue(Issue{Level: LevelError, Message: "broken", Document: &doc, Suggestion: "http://archive.example/page"})
+	iS.AddIssue(Issue{Level: LevelDebug, Message: "noise", Document: &doc})
+
+	started := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	iS.WriteSinks(RunMeta{Version: "1.0", DirectoryPath: "_site", Started: started,
+		Finished: started.Add(time.Second), Documents: 7})
+
+	logBytes, err := ioutil.ReadFile(path.Join(dir, "debug.log"))
+	assert.Equals(t, "file error", err, nil)
+	log := string(logBytes)
+	assert.IsTrue(t, "log header", strings.HasPrefix(log, "# htmltest 1.0 on \"_site\"\n"))
+	assert.IsTrue(t, "log meta", strings.Contains(log, "finished 2020-01-02T03:04:06Z, 7 documents"))
+	assert.IsTrue(t, "log error", strings.Contains(log, " ERROR   broken --- dir/page.html --> <nil> (suggest http://archive.example/page)"))
+	assert.IsTrue(t, "log debug", strings.Contains(log, " DEBUG   noise"))
+
+	jsonBytes, err := ioutil.ReadFile(path.Join(dir, "report.json"))
+	assert.Equals(t, "file error", err, nil)
+	var report struct {
+		jsonReport
+		Issues []jsonIssue `json:"issues"`
+	}
+	assert.Equals(t, "json error", json.Unmarshal(jsonBytes, &report), nil)
+	assert.Equals(t, "json documents", report.Documents, 7)
+	assert.Equals(t, "json issue count", len(report.Issues), 1)
+	assert.Equals(t, "json issue", report.Issues[0].Document, "dir/page.html")
+	assert.Equals(t, "json suggestion", report.Issues[0].Suggestion, "http://archive.example/page")
+	assert.Equals(t, "json counts", report.Counts["error"], 1)
+
+	// Still a document with no issues to stream
+	empty := NewIssueStore(LevelNone, false)
+	empty.WriteSink(Sink{Path: path.Join(dir, "empty.json"), Level: LevelWarning, Format: FormatJSON}, RunMeta{})
+	jsonBytes, err = ioutil.ReadFile(path.Join(dir, "empty.json"))
+	assert.Equals(t, "file error", err, nil)
+	assert.Equals(t, "json error", json.Unmarshal(jsonBytes, &report), nil)
+	assert.Equals(t, "json no issues", len(report.Issues), 0)
+}
@@ -0,0 +1,162 @@
+package issues
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/wjdp/htmltest/htmldoc"
+	"github.com/wjdp/htmltest/output"
+	"golang.org/x/net/html"
+)
+
+// issueSpill : a file retained issues are moved to once the store holds more
+// than threshold, one JSON record per line
+type issueSpill struct {
+	path      string
+	file      *os.File
+	writer    *bufio.Writer
+	threshold int // Retained issues to hold in memory before spilling
+	next      int // Retained issue count which triggers the next spill
+	count     int // Issues in the file
+}
+
+// spilledIssue : an issue as written to the spill file, enough to rebuild it
+// for sinks and the Markdown report
+type spilledIssue struct {
+	Time       time.Time   `json:"t"`
+	Level      int         `json:"l"`
+	Message    string      `json:"m"`
+	Suggestion string      `json:"s,omitempty"`
+	Check      string      `json:"c,omitempty"`
+	Document   string      `json:"d,omitempty"`
+	Reference  *spilledRef `json:"r,omitempty"`
+}
+
+// spilledRef : the parts of a reference issues are rendered with
+type spilledRef struct {
+	Path  string `json:"p"`
+	Field string `json:"f,omitempty"`
+	Node  string `json:"n,omitempty"`
+}
+
+// SetSpill : Once more than threshold issues are retained, move those for
+// documents which have been printed, and site wide ones, to a file at
+// filePath. Keeps memory bounded on huge runs, sinks read them back when
+// written and WriteSinks removes the file. Replaces any existing file,
+// threshold zero disables.
+func (iS *IssueStore) SetSpill(filePath string, threshold int) error {
+	iS.storeMutex.Lock()
+	defer iS.storeMutex.Unlock()
+	iS.closeSpill()
+	if threshold <= 0 {
+		return nil
+	}
+	f, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	iS.spill = &issueSpill{
+		path:      filePath,
+		file:      f,
+		writer:    bufio.NewWriter(f),
+		threshold: threshold,
+		next:      threshold,
+	}
+	return nil
+}
+
+// Move spillable issues out of memory, caller holds storeMutex. Issues for
+// documents not yet printed stay so PrintDocumentIssues can show them.
+func (iS *IssueStore) spillIssues() {
+	enc := json.NewEncoder(iS.spill.writer)
+	kept := make([]*Issue, 0)
+	for _, issue := range iS.issues {
+		primary := issue.primary()
+		if !iS.printImmediately && primary != textNil && !iS.printed[primary] {
+			kept = append(kept, issue)
+			continue
+		}
+		output.CheckErrorPanic(enc.Encode(issue.spilled()))
+		iS.spill.count++
+		delete(iS.issuesByDoc, primary)
+	}
+	iS.issues = kept
+	// Don't spill again until threshold more have arrived
+	iS.spill.next = len(kept) + iS.spill.threshold
+}
+
+// Record of issue for the spill file.
+func (issue *Issue) spilled() spilledIssue {
+	sI := spilledIssue{
+		Time:       issue.time,
+		Level:      issue.Level,
+		Message:    issue.Message,
+		Suggestion: issue.Suggestion,
+		Check:      issue.Check,
+	}
+	if pri := issue.primary(); pri != textNil {
+		sI.Document = pri
+	}
+	if ref := issue.Reference; ref != nil {
+		sI.Reference = &spilledRef{Path: ref.Path, Field: ref.Field}
+		if ref.Node != nil {
+			sI.Reference.Node = ref.Node.Data
+		}
+	}
+	return sI
+}
+
+// Read the spilled issues back one at a time, owned by iS, calling fn with
+// each. Caller holds storeMutex.
+func (sp *issueSpill) read(iS *IssueStore, fn func(*Issue)) {
+	output.CheckErrorPanic(sp.writer.Flush())
+	f, err := os.Open(sp.path)
+	output.CheckErrorPanic(err)
+	defer f.Close()
+
+	// Share documents and nodes between issues
+	documents := make(map[string]*htmldoc.Document)
+	nodes := make(map[string]*html.Node)
+	dec := json.NewDecoder(bufio.NewReader(f))
+	for dec.More() {
+		var sI spilledIssue
+		output.CheckErrorPanic(dec.Decode(&sI))
+		issue := &Issue{
+			Level:      sI.Level,
+			Message:    sI.Message,
+			Suggestion: sI.Suggestion,
+			Check:      sI.Check,
+			store:      iS,
+			time:       sI.Time,
+		}
+		if sI.Document != "" {
+			if documents[sI.Document] == nil {
+				documents[sI.Document] = &htmldoc.Document{SitePath: sI.Document}
+			}
+			issue.Document = documents[sI.Document]
+		}
+		if sI.Reference != nil {
+			issue.Reference = &htmldoc.Reference{Path: sI.Reference.Path, Field: sI.Reference.Field}
+			if sI.Reference.Node != "" {
+				if nodes[sI.Reference.Node] == nil {
+					nodes[sI.Reference.Node] = &html.Node{Type: html.ElementNode, Data: sI.Reference.Node}
+				}
+				issue.Reference.Node = nodes[sI.Reference.Node]
+			}
+		}
+		fn(issue)
+	}
+}
+
+// Close and remove the spill file, caller holds storeMutex. Spilled issues are
+// gone after this, the store goes on counting.
+func (iS *IssueStore) closeSpill() {
+	if iS.spill == nil {
+		return
+	}
+	output.CheckErrorPanic(iS.spill.file.Close())
+	output.CheckErrorPanic(os.Remove(iS.spill.path))
+	iS.spill = nil
+}
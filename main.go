@@ -6,6 +6,7 @@ import (
 	"github.com/docopt/docopt-go"
 	"github.com/fatih/color"
 	"github.com/wjdp/htmltest/htmltest"
+	"github.com/wjdp/htmltest/issues"
 	"github.com/wjdp/htmltest/output"
 	"gopkg.in/yaml.v2"
 	"io/ioutil"
@@ -30,15 +31,25 @@ func main() {
 
 Usage:
   htmltest [options] [<path>]
+  htmltest why [options] <url>
   htmltest -v --version
   htmltest -h --help
 
 Options:
   <path>                       Path to directory or file to test, if omitted we
                                attempt to read from .htmltest.yml.
+  <url>                        External URL to diagnose, showing the cache entry,
+                               redirects, headers, timing, certificates and
+                               resulting issue.
   -c FILE, --conf FILE         Custom path to config file.
   -h, --help                   Show this text.
   -l LEVEL, --log-level LEVEL  Logging level, 0-3: debug, info, warning, error.
+  -f, --only-failed            Only re-check documents and external links which
+                               failed in the previous run.
+  -p NAME, --preset NAME       Static site generator preset: docusaurus, eleventy,
+                               hugo, jekyll, mkdocs, sphinx or none. Detected
+                               from the current directory when there's no
+                               config file or <path>.
   -s, --skip-external          Skip external link checks, may shorten execution
                                time considerably.
   -v, --version                Show version and build time.
@@ -55,14 +66,29 @@ Options:
 	// See https://no-color.org/
 	color.NoColor = os.Getenv("NO_COLOR") != ""
 
+	if arguments["why"].(bool) {
+		os.Exit(runWhy(arguments))
+	}
+
 	if arguments["--conf"] != nil {
 		// Config file specified
 		options = parseConfFile(arguments, arguments["--conf"].(string), true)
 	} else if arguments["<path>"] != nil {
 		// Path specified
 		options = parseCLIArgs(arguments)
+	} else if _, err := os.Stat(".htmltest.yml"); !os.IsNotExist(err) {
+		// Default config file
+		options = parseConfFile(arguments, ".htmltest.yml", false)
+	} else if arguments["--preset"] != nil && arguments["--preset"] != "none" {
+		// No config, the chosen preset provides the path
+		options = parseCLIArgs(arguments)
+	} else if preset := detectPreset(arguments); preset != "" {
+		// Nothing configured at all, detect a preset to provide the path
+		fmt.Println("using", preset, "preset, add .htmltest.yml or pass --preset none to change")
+		options = parseCLIArgs(arguments)
+		options["Preset"] = preset
 	} else {
-		// Other
+		// Other, reports the missing default config
 		options = parseConfFile(arguments, ".htmltest.yml", false)
 	}
 
@@ -75,6 +101,14 @@ Options:
 
 type optsMap map[string]interface{}
 
+// Detect a preset from the working directory, unless --preset was given.
+func detectPreset(arguments map[string]interface{}) string {
+	if arguments["--preset"] != nil {
+		return ""
+	}
+	return htmltest.DetectPreset(".")
+}
+
 func parseConfFile(arguments map[string]interface{}, path string, explicit bool) optsMap {
 	yamlFile, err := ioutil.ReadFile(path)
 
@@ -147,6 +181,18 @@ func augmentWithCLIArgs(options optsMap, arguments map[string]interface{}) {
 		}
 	}
 
+	if arguments["--preset"] != nil {
+		preset := arguments["--preset"].(string)
+		if preset == "none" {
+			preset = ""
+		}
+		options["Preset"] = preset
+	}
+
+	if arguments["--only-failed"].(bool) {
+		options["OnlyFailed"] = true
+	}
+
 	if arguments["--skip-external"].(bool) {
 		output.Warn("Skipping the checking of external links.")
 		options["CheckExternal"] = false
@@ -157,7 +203,16 @@ func augmentWithCLIArgs(options optsMap, arguments map[string]interface{}) {
 func run(options optsMap) int {
 	timeStart := time.Now()
 
-	fmt.Println("htmltest started at", timeStart.Format("03:04:05"), "on", options["DirectoryPath"])
+	directoryPath := options["DirectoryPath"]
+	if directoryPath == nil {
+		// Perhaps provided by the preset
+		if preset, ok := options["Preset"].(string); ok {
+			if optsPreset, err := htmltest.PresetOptions(preset); err == nil {
+				directoryPath = optsPreset["DirectoryPath"]
+			}
+		}
+	}
+	fmt.Println("htmltest started at", timeStart.Format("03:04:05"), "on", directoryPath)
 	fmt.Println(cmdSeparator)
 
 	// Run htmltest
@@ -171,6 +226,11 @@ func run(options optsMap) int {
 	timeEnd := time.Now()
 	numErrors := hT.CountErrors()
 
+	if onlyFailed, _ := options["OnlyFailed"].(bool); onlyFailed {
+		printFixed(hT)
+	}
+	printHostsDown(hT)
+
 	if numErrors == 0 {
 		color.Set(color.FgHiGreen)
 		fmt.Println("✔✔✔ passed in", timeEnd.Sub(timeStart))
@@ -193,3 +253,57 @@ func run(options optsMap) int {
 	return 1
 
 }
+
+// List hosts marked down, their URLs weren't all checked.
+func printHostsDown(hT *htmltest.HTMLTest) {
+	outages := hT.HostsDown()
+	if len(outages) == 0 {
+		return
+	}
+	color.Set(color.FgHiRed)
+	fmt.Println("hosts down:")
+	for _, outage := range outages {
+		fmt.Println("  ✘", outage.Host, "-", len(outage.URLs), "URLs affected")
+	}
+	color.Unset()
+}
+
+// List what failed last run and passes now, for --only-failed.
+func printFixed(hT *htmltest.HTMLTest) {
+	documents, urls := hT.FixedSinceLastRun()
+	if len(documents)+len(urls) == 0 {
+		return
+	}
+	color.Set(color.FgHiGreen)
+	fmt.Println("fixed since last run:")
+	for _, item := range append(documents, urls...) {
+		fmt.Println("  ✔", item)
+	}
+	color.Unset()
+}
+
+// Diagnose a single URL with the configured options, for htmltest why.
+func runWhy(arguments map[string]interface{}) int {
+	var options optsMap
+	if arguments["--conf"] != nil {
+		options = parseConfFile(arguments, arguments["--conf"].(string), true)
+	} else if _, err := os.Stat(".htmltest.yml"); err == nil {
+		options = parseConfFile(arguments, ".htmltest.yml", false)
+	} else {
+		options = parseCLIArgs(arguments)
+	}
+	options["Version"] = strings.TrimLeft(version, "v")
+
+	diagnosis, err := htmltest.Why(options, arguments["<url>"].(string))
+	if err != nil {
+		output.AbortWith(err)
+	}
+	diagnosis.Write(os.Stdout)
+
+	for _, issue := range diagnosis.Issues {
+		if issue.Level == issues.LevelError {
+			return 1
+		}
+	}
+	return 0
+}
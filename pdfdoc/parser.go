@@ -0,0 +1,503 @@
+package pdfdoc
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"unicode/utf16"
+)
+
+// PDF objects are decoded to these types, along with bool, int, float64 and
+// nil
+type (
+	name   string                 // /Name
+	array  []interface{}          // [ ... ]
+	dict   map[name]interface{}   // << ... >>
+	objRef struct{ num, gen int } // 1 0 R
+	stream struct {               // << ... >> stream ... endstream
+		dict dict
+		data []byte // Raw, as in the file
+	}
+)
+
+var errEOF = errors.New("unexpected end of file")
+
+// Largest decoded stream we'll hold in memory, guarding against streams that
+// inflate far beyond their size in the file
+const maxDecodedStream = 16 << 20
+
+// Starts of indirect objects, "12 0 obj"
+var objRegexp = regexp.MustCompile(`(\d+)[\x00\t\n\f\r ]+(\d+)[\x00\t\n\f\r ]+obj`)
+
+// parser : reads objects from b starting at pos
+type parser struct {
+	b   []byte
+	pos int
+}
+
+func isSpace(c byte) bool {
+	return c == 0 || c == '\t' || c == '\n' || c == '\f' || c == '\r' || c == ' '
+}
+
+func isDelimiter(c byte) bool {
+	return bytes.IndexByte([]byte("()<>[]{}/%"), c) >= 0
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// Skip whitespace and comments.
+func (p *parser) skipSpace() {
+	for p.pos < len(p.b) {
+		switch c := p.b[p.pos]; {
+		case isSpace(c):
+			p.pos++
+		case c == '%':
+			for p.pos < len(p.b) && p.b[p.pos] != '\n' && p.b[p.pos] != '\r' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (p *parser) hasPrefix(s string) bool {
+	return bytes.HasPrefix(p.b[p.pos:], []byte(s))
+}
+
+// Read a run of regular characters, such as a keyword or number.
+func (p *parser) token() string {
+	start := p.pos
+	for p.pos < len(p.b) && !isSpace(p.b[p.pos]) && !isDelimiter(p.b[p.pos]) {
+		p.pos++
+	}
+	return string(p.b[start:p.pos])
+}
+
+// Read the next direct object, or reference.
+func (p *parser) object() (interface{}, error) {
+	p.skipSpace()
+	if p.pos >= len(p.b) {
+		return nil, errEOF
+	}
+	switch c := p.b[p.pos]; {
+	case c == '/':
+		return p.name(), nil
+	case c == '(':
+		return p.literalString()
+	case p.hasPrefix("<<"):
+		return p.dict()
+	case c == '<':
+		return p.hexString()
+	case c == '[':
+		return p.array()
+	case c == '+' || c == '-' || c == '.' || isDigit(c):
+		return p.number()
+	}
+	start := p.pos
+	switch tok := p.token(); tok {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unexpected %q at offset %d", p.b[start:min(start+10, len(p.b))], start)
+}
+
+// Read a number, or a reference if it's followed by a generation and R.
+func (p *parser) number() (interface{}, error) {
+	start := p.pos
+	tok := p.token()
+	if n, err := strconv.Atoi(tok); err == nil {
+		if n < 0 {
+			return n, nil
+		}
+		after := p.pos
+		p.skipSpace()
+		genStart := p.pos
+		for p.pos < len(p.b) && isDigit(p.b[p.pos]) {
+			p.pos++
+		}
+		if p.pos > genStart {
+			gen, _ := strconv.Atoi(string(p.b[genStart:p.pos]))
+			p.skipSpace()
+			if p.hasPrefix("R") && (p.pos+1 == len(p.b) || isSpace(p.b[p.pos+1]) || isDelimiter(p.b[p.pos+1])) {
+				p.pos++
+				return objRef{n, gen}, nil
+			}
+		}
+		p.pos = after
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(tok, 64); err == nil {
+		return f, nil
+	}
+	return nil, fmt.Errorf("bad number %q at offset %d", tok, start)
+}
+
+// Read a name, decoding #xx escapes.
+func (p *parser) name() name {
+	p.pos++ // Skip /
+	tok := []byte(p.token())
+	var b []byte
+	for i := 0; i < len(tok); i++ {
+		if tok[i] == '#' && i+2 < len(tok) {
+			if v, err := strconv.ParseUint(string(tok[i+1:i+3]), 16, 8); err == nil {
+				b = append(b, byte(v))
+				i += 2
+				continue
+			}
+		}
+		b = append(b, tok[i])
+	}
+	return name(b)
+}
+
+// Read a (literal string), which may nest balanced parentheses.
+func (p *parser) literalString() (string, error) {
+	p.pos++ // Skip (
+	var b []byte
+	depth := 1
+	for p.pos < len(p.b) {
+		c := p.b[p.pos]
+		p.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return textString(b), nil
+			}
+		case '\\':
+			if p.pos >= len(p.b) {
+				return "", errEOF
+			}
+			c = p.b[p.pos]
+			p.pos++
+			switch c {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				// Line continuation
+				if p.pos < len(p.b) && p.b[p.pos] == '\n' {
+					p.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if c >= '0' && c <= '7' {
+					v := int(c - '0')
+					for i := 0; i < 2 && p.pos < len(p.b) && p.b[p.pos] >= '0' && p.b[p.pos] <= '7'; i++ {
+						v = v*8 + int(p.b[p.pos]-'0')
+						p.pos++
+					}
+					c = byte(v)
+				}
+			}
+		}
+		b = append(b, c)
+	}
+	return "", errEOF
+}
+
+// Read a <hex string>.
+func (p *parser) hexString() (string, error) {
+	p.pos++ // Skip <
+	var digits []byte
+	for p.pos < len(p.b) && p.b[p.pos] != '>' {
+		if !isSpace(p.b[p.pos]) {
+			digits = append(digits, p.b[p.pos])
+		}
+		p.pos++
+	}
+	if p.pos >= len(p.b) {
+		return "", errEOF
+	}
+	p.pos++ // Skip >
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	b := make([]byte, len(digits)/2)
+	for i := range b {
+		v, err := strconv.ParseUint(string(digits[2*i:2*i+2]), 16, 8)
+		if err != nil {
+			return "", fmt.Errorf("bad hex string at offset %d", p.pos)
+		}
+		b[i] = byte(v)
+	}
+	return textString(b), nil
+}
+
+// Decode a string as UTF-16BE when it has a byte order mark, otherwise treat
+// it as PDFDocEncoding, close enough to Latin-1 for URLs and names.
+func textString(b []byte) string {
+	if len(b) >= 2 && b[0] == 0xfe && b[1] == 0xff {
+		u := make([]uint16, 0, len(b)/2)
+		for i := 2; i+1 < len(b); i += 2 {
+			u = append(u, uint16(b[i])<<8|uint16(b[i+1]))
+		}
+		return string(utf16.Decode(u))
+	}
+	if bytes.HasPrefix(b, []byte("\xef\xbb\xbf")) {
+		return string(b[3:])
+	}
+	r := make([]rune, len(b))
+	for i, c := range b {
+		r[i] = rune(c)
+	}
+	return string(r)
+}
+
+func (p *parser) array() (array, error) {
+	p.pos++ // Skip [
+	a := array{}
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.b) {
+			return nil, errEOF
+		}
+		if p.b[p.pos] == ']' {
+			p.pos++
+			return a, nil
+		}
+		v, err := p.object()
+		if err != nil {
+			return nil, err
+		}
+		a = append(a, v)
+	}
+}
+
+func (p *parser) dict() (dict, error) {
+	p.pos += 2 // Skip <<
+	d := dict{}
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.b) {
+			return nil, errEOF
+		}
+		if p.hasPrefix(">>") {
+			p.pos += 2
+			return d, nil
+		}
+		if p.b[p.pos] != '/' {
+			return nil, fmt.Errorf("dictionary key is not a name at offset %d", p.pos)
+		}
+		key := p.name()
+		v, err := p.object()
+		if err != nil {
+			return nil, err
+		}
+		d[key] = v
+	}
+}
+
+// Read an indirect object's body, which starts at pos, leaving pos after it.
+func (p *parser) indirect() (interface{}, error) {
+	v, err := p.object()
+	if err != nil {
+		return nil, err
+	}
+	d, ok := v.(dict)
+	if !ok {
+		return v, nil
+	}
+	p.skipSpace()
+	if !p.hasPrefix("stream") {
+		return d, nil
+	}
+	p.pos += len("stream")
+	// The keyword is followed by CRLF or LF
+	if p.hasPrefix("\r\n") {
+		p.pos += 2
+	} else if p.hasPrefix("\n") || p.hasPrefix("\r") {
+		p.pos++
+	}
+	return stream{dict: d, data: p.streamData(d)}, nil
+}
+
+// The raw bytes of a stream starting at pos. A direct Length is trusted if
+// endstream follows it, otherwise we search for endstream.
+func (p *parser) streamData(d dict) []byte {
+	start := p.pos
+	if length, ok := d["Length"].(int); ok && length >= 0 && start+length <= len(p.b) {
+		p.pos = start + length
+		p.skipSpace()
+		if p.hasPrefix("endstream") {
+			p.pos += len("endstream")
+			return p.b[start : start+length]
+		}
+	}
+	end := bytes.Index(p.b[start:], []byte("endstream"))
+	if end < 0 {
+		p.pos = len(p.b)
+		return p.b[start:]
+	}
+	p.pos = start + end + len("endstream")
+	data := p.b[start : start+end]
+	// Drop the end of line before endstream
+	data = bytes.TrimSuffix(data, []byte("\n"))
+	return bytes.TrimSuffix(data, []byte("\r"))
+}
+
+// Apply a stream's filters, nil if they aren't supported or the result would
+// exceed maxDecodedStream. Only FlateDecode without a predictor is supported,
+// which is what object streams use in practice.
+func decodeStream(d dict, data []byte) []byte {
+	var filters array
+	switch f := d["Filter"].(type) {
+	case nil:
+		return data
+	case name:
+		filters = array{f}
+	case array:
+		filters = f
+	}
+	for _, f := range filters {
+		if f != name("FlateDecode") {
+			return nil
+		}
+		if parms, ok := d["DecodeParms"].(dict); ok {
+			if predictor, _ := parms["Predictor"].(int); predictor > 1 {
+				return nil
+			}
+		}
+		r, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil
+		}
+		// Keep what decompressed from truncated streams
+		data, _ = ioutil.ReadAll(io.LimitReader(r, maxDecodedStream+1))
+		if len(data) > maxDecodedStream {
+			return nil
+		}
+	}
+	return data
+}
+
+// objects : every indirect object in a file by number, the last definition
+// winning as incremental updates append to the file
+type objects map[int]interface{}
+
+// Follow v if it's a reference.
+func (objs objects) resolve(v interface{}) interface{} {
+	if r, ok := v.(objRef); ok {
+		return objs[r.num]
+	}
+	return v
+}
+
+// Resolve v as a dictionary, or a stream's dictionary.
+func (objs objects) dict(v interface{}) dict {
+	switch d := objs.resolve(v).(type) {
+	case dict:
+		return d
+	case stream:
+		return d.dict
+	}
+	return nil
+}
+
+// Resolve v as an array.
+func (objs objects) array(v interface{}) array {
+	a, _ := objs.resolve(v).(array)
+	return a
+}
+
+// Read every indirect object in b, along with trailer dictionaries, by
+// scanning rather than trusting the cross reference table.
+func readObjects(b []byte) (objects, []dict) {
+	objs := objects{}
+	trailers := make([]dict, 0)
+	p := &parser{b: b}
+
+	end := 0
+	for _, m := range objRegexp.FindAllSubmatchIndex(b, -1) {
+		// Inside the previous object's stream or a number's tail
+		if m[0] < end || (m[0] > 0 && !isSpace(b[m[0]-1]) && !isDelimiter(b[m[0]-1])) {
+			continue
+		}
+		num, _ := strconv.Atoi(string(b[m[2]:m[3]]))
+		p.pos = m[1]
+		v, err := p.indirect()
+		if err != nil {
+			end = m[1]
+			continue
+		}
+		end = p.pos
+		objs[num] = v
+		if s, ok := v.(stream); ok {
+			switch s.dict["Type"] {
+			case name("ObjStm"):
+				readObjectStream(s, objs)
+			case name("XRef"):
+				trailers = append(trailers, s.dict)
+			}
+		}
+	}
+
+	for i := 0; ; {
+		at := bytes.Index(b[i:], []byte("trailer"))
+		if at < 0 {
+			break
+		}
+		p.pos = i + at + len("trailer")
+		if v, err := p.object(); err == nil {
+			if d, ok := v.(dict); ok {
+				trailers = append(trailers, d)
+			}
+		}
+		i += at + len("trailer")
+	}
+	return objs, trailers
+}
+
+// Read the objects packed into an object stream.
+func readObjectStream(s stream, objs objects) {
+	n, _ := s.dict["N"].(int)
+	first, _ := s.dict["First"].(int)
+	data := decodeStream(s.dict, s.data)
+	if data == nil || first < 0 || first > len(data) {
+		return
+	}
+	header := &parser{b: data[:first]}
+	for i := 0; i < n; i++ {
+		num, err1 := header.object()
+		offset, err2 := header.object()
+		numInt, ok1 := num.(int)
+		offsetInt, ok2 := offset.(int)
+		if err1 != nil || err2 != nil || !ok1 || !ok2 || offsetInt < 0 || first+offsetInt > len(data) {
+			return
+		}
+		p := &parser{b: data, pos: first + offsetInt}
+		if v, err := p.object(); err == nil {
+			objs[numInt] = v
+		}
+	}
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,82 @@
+package pdfdoc
+
+import (
+	"bytes"
+	"compress/zlib"
+	"strconv"
+	"testing"
+
+	"github.com/daviddengcn/go-assert"
+)
+
+func tParse(t *testing.T, s string) interface{} {
+	p := &parser{b: []byte(s)}
+	v, err := p.object()
+	assert.Equals(t, "parse error", err, nil)
+	return v
+}
+
+func TestParserScalars(t *testing.T) {
+	assert.Equals(t, "int", tParse(t, " 42 "), 42)
+	assert.Equals(t, "negative", tParse(t, "-3"), -3)
+	assert.Equals(t, "real", tParse(t, "1.5"), 1.5)
+	assert.Equals(t, "bool", tParse(t, "true"), true)
+	assert.Equals(t, "null", tParse(t, "null"), nil)
+	assert.Equals(t, "ref", tParse(t, "12 0 R"), objRef{12, 0})
+	assert.Equals(t, "name", tParse(t, "/A#20B"), name("A B"))
+}
+
+func TestParserStrings(t *testing.T) {
+	assert.Equals(t, "literal", tParse(t, `(a (nested) \(escaped\) \101\
+b)`), "a (nested) (escaped) Ab")
+	assert.Equals(t, "hex", tParse(t, "<68 74 7>"), "htp")
+	assert.Equals(t, "utf-16", tParse(t, "<FEFF00E9>"), "é")
+	assert.Equals(t, "latin-1", tParse(t, `(\351)`), "é")
+}
+
+func TestParserContainers(t *testing.T) {
+	v := tParse(t, "<< /Kids [1 0 R 2 0 R] /Count 2 % comment\n /Sub << >> >>")
+	d, ok := v.(dict)
+	assert.IsTrue(t, "dict", ok)
+	assert.StringEquals(t, "array", d["Kids"], array{objRef{1, 0}, objRef{2, 0}})
+	assert.Equals(t, "count", d["Count"], 2)
+	assert.StringEquals(t, "nested", d["Sub"], dict{})
+	// Numbers in arrays aren't mistaken for references
+	assert.StringEquals(t, "numbers", tParse(t, "[0 0 612 792]"), array{0, 0, 612, 792})
+}
+
+func TestParserErrors(t *testing.T) {
+	for _, s := range []string{"(unterminated", "<< /Key", "[1 2", "<< 1 2 >>", "}"} {
+		p := &parser{b: []byte(s)}
+		_, err := p.object()
+		assert.NotEquals(t, "error for "+s, err, nil)
+	}
+}
+
+func tDeflate(b []byte) []byte {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	w.Write(b)
+	w.Close()
+	return buf.Bytes()
+}
+
+func TestParserStreamRaw(t *testing.T) {
+	data := tDeflate([]byte("hello"))
+	s := "<< /Filter /FlateDecode /Length " + strconv.Itoa(len(data)) + " >>\nstream\n" +
+		string(data) + "\nendstream"
+	p := &parser{b: []byte(s)}
+	v, err := p.indirect()
+	assert.Equals(t, "error", err, nil)
+	assert.StringEquals(t, "raw data", v.(stream).data, data)
+	assert.StringEquals(t, "decoded", decodeStream(v.(stream).dict, v.(stream).data), []byte("hello"))
+}
+
+func TestDecodeStreamLimit(t *testing.T) {
+	d := dict{"Filter": name("FlateDecode")}
+	bomb := tDeflate(make([]byte, maxDecodedStream+1))
+	assert.Equals(t, "over limit", decodeStream(d, bomb) == nil, true)
+	atLimit := tDeflate(make([]byte, maxDecodedStream))
+	assert.Equals(t, "at limit", len(decodeStream(d, atLimit)), maxDecodedStream)
+	assert.Equals(t, "unsupported", decodeStream(dict{"Filter": name("LZWDecode")}, []byte("x")) == nil, true)
+}
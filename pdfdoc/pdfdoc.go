@@ -0,0 +1,203 @@
+// Package pdfdoc : htmltest PDF reader, extracts the links and named
+// destinations of PDF documents.
+package pdfdoc
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"sort"
+)
+
+// ErrEncrypted : returned for encrypted PDFs, whose strings we can't read.
+var ErrEncrypted = errors.New("encrypted PDF")
+
+// Link : a link annotation on a page.
+type Link struct {
+	Page int    // 1-based number of the page the link is on
+	URI  string // Target of URI and GoToR actions, empty for links within the PDF
+	Dest string // Named destination jumped to within the PDF, empty if none
+}
+
+// Document : the parts of a PDF htmltest checks.
+type Document struct {
+	Pages int             // Page count
+	Links []Link          // Link annotations in page order
+	Dests map[string]bool // Named destinations
+}
+
+// Open : Read the PDF at filePath.
+func Open(filePath string) (*Document, error) {
+	b, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(b)
+}
+
+// Parse : Read a PDF from its bytes. Object streams are supported, PDFs
+// which are encrypted or use other compression for their structure aren't.
+func Parse(b []byte) (*Document, error) {
+	header := bytes.Index(b, []byte("%PDF-"))
+	if header < 0 || header > 1024 {
+		return nil, errors.New("no PDF header")
+	}
+
+	objs, trailers := readObjects(b)
+	var root dict
+	// Later trailers belong to incremental updates and take precedence
+	for i := len(trailers) - 1; i >= 0 && root == nil; i-- {
+		if _, ok := trailers[i]["Encrypt"]; ok {
+			return nil, ErrEncrypted
+		}
+		root = objs.dict(trailers[i]["Root"])
+	}
+	if root == nil {
+		root = findCatalog(objs)
+	}
+	if root == nil {
+		return nil, errors.New("no document catalog")
+	}
+
+	doc := &Document{Links: make([]Link, 0), Dests: make(map[string]bool)}
+	visited := make(map[interface{}]bool)
+	var walk func(node interface{})
+	walk = func(node interface{}) {
+		// Guard against cycles in malformed page trees
+		if r, ok := node.(objRef); ok {
+			if visited[r] {
+				return
+			}
+			visited[r] = true
+		}
+		d := objs.dict(node)
+		if d == nil {
+			return
+		}
+		if kids, ok := objs.resolve(d["Kids"]).(array); ok && d["Type"] != name("Page") {
+			for _, kid := range kids {
+				walk(kid)
+			}
+			return
+		}
+		doc.Pages++
+		for _, annot := range objs.array(d["Annots"]) {
+			if link, ok := readLink(objs, objs.dict(annot)); ok {
+				link.Page = doc.Pages
+				doc.Links = append(doc.Links, link)
+			}
+		}
+	}
+	walk(root["Pages"])
+
+	// PDF 1.1 destinations dictionary
+	for key := range objs.dict(root["Dests"]) {
+		doc.Dests[string(key)] = true
+	}
+	// Later name tree
+	readNameTree(objs, objs.dict(root["Names"])["Dests"], doc.Dests, visited)
+	return doc, nil
+}
+
+// Find the document catalog without a trailer to point to it, the highest
+// numbered one being the most likely to be current.
+func findCatalog(objs objects) dict {
+	nums := make([]int, 0, len(objs))
+	for num := range objs {
+		nums = append(nums, num)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(nums)))
+	for _, num := range nums {
+		if d, ok := objs[num].(dict); ok && d["Type"] == name("Catalog") {
+			return d
+		}
+	}
+	return nil
+}
+
+// Read a link annotation, ok is false for other annotations and links we
+// can't check.
+func readLink(objs objects, annot dict) (Link, bool) {
+	if annot == nil || annot["Subtype"] != name("Link") {
+		return Link{}, false
+	}
+	if dest := destName(objs.resolve(annot["Dest"])); dest != "" {
+		return Link{Dest: dest}, true
+	}
+	action := objs.dict(annot["A"])
+	switch action["S"] {
+	case name("URI"):
+		if uri, ok := objs.resolve(action["URI"]).(string); ok {
+			return Link{URI: uri}, true
+		}
+	case name("GoTo"):
+		if dest := destName(objs.resolve(action["D"])); dest != "" {
+			return Link{Dest: dest}, true
+		}
+	case name("GoToR"):
+		file := fileSpec(objs, action["F"])
+		if file == "" {
+			return Link{}, false
+		}
+		if dest := destName(objs.resolve(action["D"])); dest != "" {
+			file += "#nameddest=" + dest
+		}
+		return Link{URI: file}, true
+	}
+	return Link{}, false
+}
+
+// The name of a named destination, empty for explicit ones such as
+// [page /Fit].
+func destName(v interface{}) string {
+	switch d := v.(type) {
+	case name:
+		return string(d)
+	case string:
+		return d
+	}
+	return ""
+}
+
+// The path of a file specification, a string or a dictionary.
+func fileSpec(objs objects, v interface{}) string {
+	switch f := objs.resolve(v).(type) {
+	case string:
+		return f
+	case dict:
+		for _, key := range []name{"UF", "F"} {
+			if s, ok := objs.resolve(f[key]).(string); ok {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// Add the keys of a name tree to names.
+func readNameTree(objs objects, node interface{}, names map[string]bool, visited map[interface{}]bool) {
+	if r, ok := node.(objRef); ok {
+		if visited[r] {
+			return
+		}
+		visited[r] = true
+	}
+	d := objs.dict(node)
+	if d == nil {
+		return
+	}
+	pairs := objs.array(d["Names"])
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if key, ok := objs.resolve(pairs[i]).(string); ok {
+			names[key] = true
+		}
+	}
+	for _, kid := range objs.array(d["Kids"]) {
+		readNameTree(objs, kid, names, visited)
+	}
+}
+
+// HasDest : Does the PDF have the given named destination?
+func (doc *Document) HasDest(dest string) bool {
+	return doc.Dests[dest]
+}
@@ -0,0 +1,48 @@
+package pdfdoc
+
+import (
+	"testing"
+
+	"github.com/daviddengcn/go-assert"
+)
+
+func TestOpenLinks(t *testing.T) {
+	doc, err := Open("fixtures/links.pdf")
+	assert.Equals(t, "error", err, nil)
+	assert.Equals(t, "pages", doc.Pages, 2)
+	assert.Equals(t, "link count", len(doc.Links), 5)
+	assert.Equals(t, "uri", doc.Links[0], Link{Page: 1, URI: "https://example.com/a(1)"})
+	assert.Equals(t, "goto", doc.Links[1], Link{Page: 1, Dest: "chapter-1"})
+	assert.Equals(t, "dest", doc.Links[2], Link{Page: 1, Dest: "missing"})
+	assert.Equals(t, "hex uri", doc.Links[3], Link{Page: 2, URI: "other.pdf"})
+	assert.Equals(t, "gotor", doc.Links[4], Link{Page: 2, URI: "other.pdf#nameddest=intro"})
+	assert.IsTrue(t, "name tree dest", doc.HasDest("chapter-1"))
+	assert.IsTrue(t, "utf-16 dest", doc.HasDest("chapter-2"))
+	assert.IsFalse(t, "missing dest", doc.HasDest("missing"))
+}
+
+func TestOpenObjectStream(t *testing.T) {
+	doc, err := Open("fixtures/objstm.pdf")
+	assert.Equals(t, "error", err, nil)
+	assert.Equals(t, "pages", doc.Pages, 1)
+	assert.Equals(t, "link count", len(doc.Links), 1)
+	assert.Equals(t, "uri", doc.Links[0].URI, "mailto:docs@example.com")
+	assert.IsTrue(t, "dests dictionary", doc.HasDest("intro"))
+}
+
+func TestOpenEncrypted(t *testing.T) {
+	_, err := Open("fixtures/encrypted.pdf")
+	assert.Equals(t, "error", err, ErrEncrypted)
+}
+
+func TestOpenMissing(t *testing.T) {
+	_, err := Open("fixtures/nothing.pdf")
+	assert.NotEquals(t, "error", err, nil)
+}
+
+func TestParseNotPDF(t *testing.T) {
+	_, err := Parse([]byte("<html></html>"))
+	assert.Equals(t, "error", err.Error(), "no PDF header")
+	_, err = Parse([]byte("%PDF-1.4\nnothing here\n"))
+	assert.Equals(t, "error", err.Error(), "no document catalog")
+}
@@ -13,10 +13,22 @@ import (
 // RefCache struct : store of cached references.
 type RefCache struct {
 	refStore     map[string]CachedRef
+	hostStore    map[string]CachedHost
+	archiveStore map[string]CachedArchive
+	failures     *RunFailures
 	rwMutex      *sync.RWMutex
 	cacheExpires time.Duration
 }
 
+// storeFile : on disk format of the store. Older stores are a plain map of
+// URL to CachedRef and are still read.
+type storeFile struct {
+	Refs     map[string]CachedRef     `json:"refs"`
+	Hosts    map[string]CachedHost    `json:"hosts"`
+	Archives map[string]CachedArchive `json:"archives,omitempty"`
+	Failures *RunFailures             `json:"failures,omitempty"`
+}
+
 // NewRefCache : Create a cached reference.
 func NewRefCache(storePath string, cacheExpiresStr string) *RefCache {
 	rS := &RefCache{}
@@ -26,6 +38,8 @@ func NewRefCache(storePath string, cacheExpiresStr string) *RefCache {
 
 	if !rS.ReadStore(storePath) {
 		rS.refStore = make(map[string]CachedRef)
+		rS.hostStore = make(map[string]CachedHost)
+		rS.archiveStore = make(map[string]CachedArchive)
 	}
 
 	return rS
@@ -43,11 +57,50 @@ func (rS *RefCache) ReadStore(storePath string) bool {
 	}
 	defer f.Close()
 
-	var refStore map[string]CachedRef
-	err = json.NewDecoder(f).Decode(&refStore)
+	var raw map[string]json.RawMessage
+	err = json.NewDecoder(f).Decode(&raw)
 	output.CheckErrorPanic(err)
 
-	rS.refStore = refStore
+	store := storeFile{}
+	if refs, ok := raw["refs"]; ok {
+		err = json.Unmarshal(refs, &store.Refs)
+		output.CheckErrorPanic(err)
+		if hosts, ok := raw["hosts"]; ok {
+			err = json.Unmarshal(hosts, &store.Hosts)
+			output.CheckErrorPanic(err)
+		}
+		if archives, ok := raw["archives"]; ok {
+			err = json.Unmarshal(archives, &store.Archives)
+			output.CheckErrorPanic(err)
+		}
+		if failures, ok := raw["failures"]; ok {
+			err = json.Unmarshal(failures, &store.Failures)
+			output.CheckErrorPanic(err)
+		}
+	} else {
+		// Old format, keys are URLs
+		store.Refs = make(map[string]CachedRef)
+		for urlStr, v := range raw {
+			var cR CachedRef
+			err = json.Unmarshal(v, &cR)
+			output.CheckErrorPanic(err)
+			store.Refs[urlStr] = cR
+		}
+	}
+	if store.Refs == nil {
+		store.Refs = make(map[string]CachedRef)
+	}
+	if store.Hosts == nil {
+		store.Hosts = make(map[string]CachedHost)
+	}
+	if store.Archives == nil {
+		store.Archives = make(map[string]CachedArchive)
+	}
+
+	rS.refStore = store.Refs
+	rS.hostStore = store.Hosts
+	rS.archiveStore = store.Archives
+	rS.failures = store.Failures
 	return true
 }
 
@@ -59,7 +112,10 @@ func (rS *RefCache) WriteStore(storePath string) {
 	output.CheckErrorPanic(err)
 	defer f.Close()
 
-	err = json.NewEncoder(f).Encode(&rS.refStore)
+	rS.rwMutex.RLock()
+	defer rS.rwMutex.RUnlock()
+	err = json.NewEncoder(f).Encode(&storeFile{Refs: rS.refStore, Hosts: rS.hostStore, Archives: rS.archiveStore,
+		Failures: rS.failures})
 	output.CheckErrorPanic(err)
 }
 
@@ -87,6 +143,17 @@ func (rS *RefCache) Get(urlStr string) (*CachedRef, bool) {
 	return nil, false
 }
 
+// Peek : Get a cached result even if it has expired, thread safe.
+func (rS *RefCache) Peek(urlStr string) (*CachedRef, bool) {
+	rS.rwMutex.RLock()
+	val, ok := rS.refStore[urlStr]
+	rS.rwMutex.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	return &val, true
+}
+
 // Save a result to the cache, thread safe.
 func (rS *RefCache) Save(urlStr string, statusCode int) {
 	cR := CachedRef{
@@ -97,3 +164,80 @@ func (rS *RefCache) Save(urlStr string, statusCode int) {
 	rS.refStore[urlStr] = cR
 	rS.rwMutex.Unlock()
 }
+
+// CachedHost struct : What's known about a host across URLs
+type CachedHost struct {
+	Profile  string // Request header profile which gets past bot blocking
+	LastSeen time.Time
+}
+
+// GetHostProfile : Get the header profile saved for host, thread safe. Empty
+// if none is saved or the entry has expired.
+func (rS *RefCache) GetHostProfile(host string) string {
+	rS.rwMutex.RLock()
+	val, ok := rS.hostStore[host]
+	rS.rwMutex.RUnlock()
+	if ok && time.Now().Before(val.LastSeen.Add(rS.cacheExpires)) {
+		return val.Profile
+	}
+	return ""
+}
+
+// SaveHostProfile : Save the header profile which works for host, thread safe.
+func (rS *RefCache) SaveHostProfile(host string, profile string) {
+	rS.rwMutex.Lock()
+	rS.hostStore[host] = CachedHost{Profile: profile, LastSeen: time.Now()}
+	rS.rwMutex.Unlock()
+}
+
+// CachedArchive struct : Result of looking up an archived copy of a URL
+type CachedArchive struct {
+	Snapshot string // URL of the closest snapshot, empty if there isn't one
+	LastSeen time.Time
+}
+
+// GetArchive : Get a cached archive lookup for urlStr, thread safe. The bool
+// is false if urlStr hasn't been looked up or the entry has expired, an empty
+// snapshot means there's no archived copy.
+func (rS *RefCache) GetArchive(urlStr string) (string, bool) {
+	rS.rwMutex.RLock()
+	val, ok := rS.archiveStore[urlStr]
+	rS.rwMutex.RUnlock()
+	if ok && time.Now().Before(val.LastSeen.Add(rS.cacheExpires)) {
+		return val.Snapshot, true
+	}
+	return "", false
+}
+
+// SaveArchive : Save the result of an archive lookup for urlStr, thread safe.
+func (rS *RefCache) SaveArchive(urlStr string, snapshot string) {
+	rS.rwMutex.Lock()
+	rS.archiveStore[urlStr] = CachedArchive{Snapshot: snapshot, LastSeen: time.Now()}
+	rS.rwMutex.Unlock()
+}
+
+// RunFailures struct : What failed in the last run, for re-checking
+type RunFailures struct {
+	Documents []string // Site paths of documents with errors
+	URLs      []string // External URLs with errors
+	Finished  time.Time
+}
+
+// GetFailures : Get the failures saved by the last run, thread safe. The bool
+// is false if none were saved. Failures don't expire.
+func (rS *RefCache) GetFailures() (RunFailures, bool) {
+	rS.rwMutex.RLock()
+	defer rS.rwMutex.RUnlock()
+	if rS.failures == nil {
+		return RunFailures{}, false
+	}
+	return *rS.failures, true
+}
+
+// SaveFailures : Save the failures of this run, replacing the last, thread
+// safe.
+func (rS *RefCache) SaveFailures(failures RunFailures) {
+	rS.rwMutex.Lock()
+	rS.failures = &failures
+	rS.rwMutex.Unlock()
+}
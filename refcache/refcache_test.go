@@ -2,6 +2,8 @@ package refcache
 
 import (
 	"github.com/daviddengcn/go-assert"
+	"io/ioutil"
+	"os"
 	"testing"
 	"time"
 )
@@ -53,3 +55,66 @@ func TestRefCacheExpiry(t *testing.T) {
 	_, okN := rS.Get(URLSTR)
 	assert.IsFalse(t, "cache invalid", okN)
 }
+
+func TestRefCacheHostProfile(t *testing.T) {
+	// host profiles are stored, written and read back
+	rS1 := NewRefCache("does-not-exist", "2s")
+	assert.Equals(t, "no profile", rS1.GetHostProfile("example.com"), "")
+	rS1.SaveHostProfile("example.com", "browser")
+	assert.Equals(t, "saved profile", rS1.GetHostProfile("example.com"), "browser")
+	STOREPATH := ".htmltest/refcache-test-hosts.json"
+	rS1.WriteStore(STOREPATH)
+	rS2 := NewRefCache(STOREPATH, "2s")
+	assert.Equals(t, "read profile", rS2.GetHostProfile("example.com"), "browser")
+}
+
+func TestRefCacheReadOldFormat(t *testing.T) {
+	// stores written before hosts were cached are still read
+	STOREPATH := ".htmltest/refcache-test-old.json"
+	os.MkdirAll(".htmltest", 0777)
+	err := ioutil.WriteFile(STOREPATH, []byte(
+		`{"http://example.com/page.html":{"StatusCode":200,"LastSeen":"`+
+			time.Now().Format(time.RFC3339)+`"}}`), 0644)
+	assert.Equals(t, "write error", err, nil)
+	rS := NewRefCache(STOREPATH, "2s")
+	cR, ok := rS.Get("http://example.com/page.html")
+	assert.IsTrue(t, "url in cache", ok)
+	assert.Equals(t, "url status in cache", cR.StatusCode, 200)
+	assert.Equals(t, "no profile", rS.GetHostProfile("example.com"), "")
+}
+
+func TestRefCacheArchive(t *testing.T) {
+	// archive lookups, including misses, are stored, written and read back
+	rS1 := NewRefCache("does-not-exist", "2s")
+	_, ok := rS1.GetArchive("http://example.com/gone")
+	assert.IsFalse(t, "not looked up", ok)
+	rS1.SaveArchive("http://example.com/gone", "http://archive.example/gone")
+	rS1.SaveArchive("http://example.com/never", "")
+	STOREPATH := ".htmltest/refcache-test-archive.json"
+	rS1.WriteStore(STOREPATH)
+	rS2 := NewRefCache(STOREPATH, "2s")
+	snapshot, ok := rS2.GetArchive("http://example.com/gone")
+	assert.IsTrue(t, "looked up", ok)
+	assert.Equals(t, "snapshot", snapshot, "http://archive.example/gone")
+	snapshot, ok = rS2.GetArchive("http://example.com/never")
+	assert.IsTrue(t, "looked up", ok)
+	assert.Equals(t, "no snapshot", snapshot, "")
+}
+
+func TestRefCacheFailures(t *testing.T) {
+	// the last run's failures are written and read back
+	rS1 := NewRefCache("does-not-exist", "2s")
+	_, ok := rS1.GetFailures()
+	assert.IsFalse(t, "none saved", ok)
+	rS1.SaveFailures(RunFailures{
+		Documents: []string{"index.html"},
+		URLs:      []string{"http://example.com/gone"},
+	})
+	STOREPATH := ".htmltest/refcache-test-failures.json"
+	rS1.WriteStore(STOREPATH)
+	rS2 := NewRefCache(STOREPATH, "2s")
+	failures, ok := rS2.GetFailures()
+	assert.IsTrue(t, "saved", ok)
+	assert.StringEquals(t, "documents", failures.Documents, []string{"index.html"})
+	assert.StringEquals(t, "urls", failures.URLs, []string{"http://example.com/gone"})
+}